@@ -72,10 +72,18 @@ func main() {
 	//telegram.SendMessage("Send Message to telegram channel")
 
 	//Start Jobs
-	go neuraLNetJobs.TrainNeuralNet(ctx)
+	trained := make(chan struct{})
+	go func() {
+		neuraLNetJobs.TrainNeuralNet(ctx)
+		close(trained)
+	}()
 
 	// Exit from application gracefully
 	graceful_exit.TerminateApp(ctx)
 
+	// Stop a running training before exiting
+	cancel()
+	<-trained
+
 	appLogger.Info("Server Exited Properly")
 }
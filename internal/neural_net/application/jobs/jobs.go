@@ -2,6 +2,7 @@ package jobs
 
 import (
 	"context"
+	"errors"
 	"main/config"
 	"main/internal/neural_net/domain/ports"
 	"main/pkg/logger"
@@ -25,5 +26,13 @@ func NewJobRunner(cfg *config.Config, logger logger.Logger, srv ports.IService)
 
 func (w *jobRunner) TrainNeuralNet(ctx context.Context) {
 	w.logger.Info("Training is started")
-	w.srv.Train()
+	err := w.srv.Train(ctx)
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		w.logger.Info("Training is canceled")
+	case err != nil:
+		w.logger.Errorf("Training failed: %v", err)
+	default:
+		w.logger.Info("Training is finished")
+	}
 }
@@ -1,187 +1,259 @@
 package services
 
 import (
-	"main/internal/neural_net/application/services/layer"
+	"context"
+	"fmt"
 	"main/internal/neural_net/application/services/solver"
-	"main/internal/neural_net/domain/utils"
+	"main/internal/neural_net/domain/entities"
 	"sync"
-	"time"
 )
 
 // BatchTrainer implements parallelized batch training
 type BatchTrainer struct {
 	*internalb
-	verbosity   int
-	batchSize   int
-	parallelism int
-	solver      solver.Solver
-	printer     *StatsPrinter
+	progress
+	config  entities.TrainerConfig
+	solver  solver.Solver
+	printer *StatsPrinter
+
+	earlyStopping *entities.EarlyStopping
+	callbacks
 }
 
 type internalb struct {
-	deltas            [][][]float64
-	partialDeltas     [][][][]float64
-	accumulatedDeltas [][][]float64
-	moments           [][][]float64
+	workspaces        []*workspace
+	partialDeltas     [][][]float64
+	accumulatedDeltas [][]float64
+	losses            []float64
+	trackLoss         bool
 }
 
-func newBatchTraining(layers []*layer.Layer, parallelism int) *internalb {
-	deltas := make([][][]float64, parallelism)
-	partialDeltas := make([][][][]float64, parallelism)
-	accumulatedDeltas := make([][][]float64, len(layers))
+func newBatchTraining(n *Neural, parallelism, shardSize int) *internalb {
+	workspaces := make([]*workspace, parallelism)
+	partialDeltas := make([][][]float64, parallelism)
 	for w := 0; w < parallelism; w++ {
-		deltas[w] = make([][]float64, len(layers))
-		partialDeltas[w] = make([][][]float64, len(layers))
-
-		for i, l := range layers {
-			deltas[w][i] = make([]float64, len(l.Neurons))
-			accumulatedDeltas[i] = make([][]float64, len(l.Neurons))
-			partialDeltas[w][i] = make([][]float64, len(l.Neurons))
-			for j, n := range l.Neurons {
-				partialDeltas[w][i][j] = make([]float64, len(n.In))
-				accumulatedDeltas[i][j] = make([]float64, len(n.In))
-			}
-		}
+		workspaces[w] = newTrainingWorkspace(n, shardSize)
+		partialDeltas[w] = n.newGradients()
 	}
 	return &internalb{
-		deltas:            deltas,
+		workspaces:        workspaces,
 		partialDeltas:     partialDeltas,
-		accumulatedDeltas: accumulatedDeltas,
+		accumulatedDeltas: n.newGradients(),
+		losses:            make([]float64, parallelism),
 	}
 }
 
 // NewBatchTrainer returns a BatchTrainer
 func NewBatchTrainer(solver solver.Solver, verbosity, batchSize, parallelism int) *BatchTrainer {
-	return &BatchTrainer{
-		solver:      solver,
-		verbosity:   verbosity,
-		batchSize:   utils.Iparam(batchSize, 1),
-		parallelism: utils.Iparam(parallelism, 1),
-		printer:     NewStatsPrinter(),
-	}
+	return NewTrainerWithConfig(solver, entities.TrainerConfig{
+		BatchSize:   batchSize,
+		Parallelism: parallelism,
+		Verbosity:   verbosity,
+	})
 }
 
-// Train trains n
-func (t *BatchTrainer) Train(n *Neural, examples, validation Examples, iterations int) {
-	t.internalb = newBatchTraining(n.Layers, t.parallelism)
+// WithEarlyStopping enables early stopping on the validation examples given
+// to Train, which then leaves n with the best weights seen
+func (t *BatchTrainer) WithEarlyStopping(es entities.EarlyStopping) *BatchTrainer {
+	t.earlyStopping = &es
+	return t
+}
 
-	train := make(Examples, len(examples))
-	copy(train, examples)
+// WithSeed makes the shuffling and dropout of every training job depend on
+// seed alone, instead of a seed drawn from the global random source
+func (t *BatchTrainer) WithSeed(seed int64) *BatchTrainer {
+	t.fixedSeed = seed
+	return t
+}
 
-	workCh := make(chan Example, t.parallelism)
-	nets := make([]*Neural, t.parallelism)
+// WithCallbacks adds callbacks notified as training progresses
+func (t *BatchTrainer) WithCallbacks(cbs ...Callback) *BatchTrainer {
+	t.add(cbs)
+	return t
+}
+
+// Train trains n for the given number of epochs, or up to it after Resume.
+// Each batch is split into contiguous shards, one per worker, so batch
+// normalization sees the rows of a whole shard at once. It stops with an
+// error wrapping ErrDiverged as soon as a weight becomes NaN or Inf, and with
+// the error of ctx between two batches once it is done. The workers exit
+// when Train returns
+func (t *BatchTrainer) Train(ctx context.Context, n *Neural, examples, validation Examples, iterations int) (*Result, error) {
+	parallelism := min(t.config.Parallelism, t.config.BatchSize)
+	shardSize := (t.config.BatchSize + parallelism - 1) / parallelism
+	if err := checkBatchNorm(n, len(examples), t.config.BatchSize, shardSize); err != nil {
+		return nil, err
+	}
 
+	s, err := newSession(ctx, n, t.solver, validation, t.config.ValidationFrequency, t.earlyStopping,
+		t.withPrinter(t.printer, t.config.Verbosity))
+	if err != nil {
+		return nil, err
+	}
+	t.internalb = newBatchTraining(n, parallelism, shardSize)
+	t.trackLoss = s.tracksLoss()
+
+	train := make(Examples, len(examples))
+
+	// Workers read the weights of n, which only change between batches, and
+	// write to buffers of their own. A single worker runs in place
+	var workChs []chan Examples
 	wg := sync.WaitGroup{}
-	for i := 0; i < t.parallelism; i++ {
-		nets[i] = NewNeural(n.Config)
-
-		go func(id int, workCh <-chan Example) {
-			n := nets[id]
-			for e := range workCh {
-				n.Forward(e.Input)
-				t.calculateDeltas(n, e.Response, id)
-				wg.Done()
+	if parallelism > 1 {
+		workChs = make([]chan Examples, parallelism)
+		for i := range workChs {
+			workChs[i] = make(chan Examples, 1)
+			go func(id int, workCh <-chan Examples) {
+				for e := range workCh {
+					t.calculateDeltas(n, e, id)
+					wg.Done()
+				}
+			}(i, workChs[i])
+		}
+		defer func() {
+			for _, ch := range workChs {
+				close(ch)
 			}
-		}(i, workCh)
+		}()
 	}
 
-	t.printer.Init(n)
-	t.solver.Init(n.NumWeights())
+	t.start(n, t.solver)
 
-	ts := time.Now()
-	for it := 1; it <= iterations; it++ {
-		train.Shuffle()
-		batches := train.SplitSize(t.batchSize)
+	for it := t.epoch + 1; it <= iterations && !s.stopped(); it++ {
+		if err := s.epochStart(it); err != nil {
+			return s.fail(t.epoch, err)
+		}
+		for w, ws := range t.workspaces {
+			t.reseed(ws.rng, it, w+1)
+		}
+		t.order(train, examples, it)
 
-		for _, b := range batches {
-			currentWeights := n.Weights()
-			for _, n := range nets {
-				n.ApplyWeights(currentWeights)
+		for bi, lo := 0, 0; lo < len(train); bi, lo = bi+1, lo+t.config.BatchSize {
+			b := train[lo:min(lo+t.config.BatchSize, len(train))]
+			if err := s.canceled(); err != nil {
+				return s.fail(t.epoch, err)
 			}
 
-			wg.Add(len(b))
-			for _, item := range b {
-				workCh <- item
+			shards := 1
+			if parallelism == 1 {
+				t.calculateDeltas(n, b, 0)
+			} else {
+				shards = 0
+				for lo := 0; lo < len(b); lo += shardSize {
+					wg.Add(1)
+					workChs[shards] <- b[lo:min(lo+shardSize, len(b))]
+					shards++
+				}
+				wg.Wait()
 			}
-			wg.Wait()
+			n.track(t.workspaces[:shards]...)
 
-			for _, wPD := range t.partialDeltas {
+			var loss float64
+			for w, wPD := range t.partialDeltas[:shards] {
+				loss += t.losses[w] / float64(len(b))
 				for i, iPD := range wPD {
 					iAD := t.accumulatedDeltas[i]
-					for j, jPD := range iPD {
-						jAD := iAD[j]
-						for k, v := range jPD {
-							jAD[k] += v
-							jPD[k] = 0
-						}
+					for k, v := range iPD {
+						iAD[k] += v
+						iPD[k] = 0
 					}
 				}
 			}
 
-			t.update(n, it)
+			if err := t.update(n, len(b), it); err != nil {
+				return s.fail(t.epoch, err)
+			}
+			if err := s.batchEnd(bi, loss); err != nil {
+				return s.fail(t.epoch, err)
+			}
+			if s.stopped() {
+				break
+			}
 		}
 
-		if t.verbosity > 0 && it%t.verbosity == 0 && len(validation) > 0 {
-			t.printer.PrintProgress(n, validation, time.Since(ts), it)
+		t.epoch = it
+		if err := s.epochEnd(); err != nil {
+			return s.fail(t.epoch, err)
 		}
 	}
-
+	return s.end(t.epoch)
 }
 
-func (t *BatchTrainer) calculateDeltas(n *Neural, ideal []float64, wid int) {
-	loss := GetLoss(n.Config.Loss)
-	deltas := t.deltas[wid]
-	partialDeltas := t.partialDeltas[wid]
-	lastDeltas := deltas[len(n.Layers)-1]
-
-	for i, n := range n.Layers[len(n.Layers)-1].Neurons {
-		lastDeltas[i] = loss.Df(
-			n.Value,
-			ideal[i],
-			n.DActivate(n.Value))
-	}
-
-	for i := len(n.Layers) - 2; i >= 0; i-- {
-		l := n.Layers[i]
-		iD := deltas[i]
-		nextD := deltas[i+1]
-		for j, n := range l.Neurons {
-			var sum float64
-			for k, s := range n.Out {
-				sum += s.Weight * nextD[k]
+// checkBatchNorm verifies that the batch normalized layers of n see at least
+// two rows in every shard of every batch of examples, the statistics of a
+// single row normalizing it to zero
+func checkBatchNorm(n *Neural, examples, batchSize, shardSize int) error {
+	for i, l := range n.Layers {
+		if l.Norm != entities.NormBatch {
+			continue
+		}
+		for lo := 0; lo < examples; lo += batchSize {
+			batch := min(batchSize, examples-lo)
+			for s := 0; s < batch; s += shardSize {
+				if rows := min(shardSize, batch-s); rows < 2 {
+					return fmt.Errorf("batch normalization of layer %d needs at least 2 rows per shard, "+
+						"%d examples in batches of %d and shards of %d leave %d", i, examples, batchSize, shardSize, rows)
+				}
 			}
-			iD[j] = n.DActivate(n.Value) * sum
 		}
 	}
+	return nil
+}
 
-	for i, l := range n.Layers {
-		iD := deltas[i]
-		iPD := partialDeltas[i]
-		for j, n := range l.Neurons {
-			jD := iD[j]
-			jPD := iPD[j]
-			for k, s := range n.In {
-				jPD[k] += jD * s.In
-			}
-		}
+// order copies examples to train in the order of epoch, which depends on
+// the seed of the job alone
+func (t *BatchTrainer) order(train, examples Examples, epoch int) {
+	copy(train, examples)
+	switch t.config.Shuffle {
+	case entities.ShuffleNone:
+		return
+	case entities.ShuffleOnce:
+		epoch = 1
+	}
+	t.reseed(t.rng, epoch, 0)
+	train.shuffle(t.rng.Intn)
+}
+
+// Checkpoint returns a snapshot of n and of the state of t after the last
+// epoch it completed. The solver must be solver.Stateful
+func (t *BatchTrainer) Checkpoint(n *Neural) (*Checkpoint, error) {
+	return t.checkpoint(n, t.solver)
+}
+
+// Resume restores the network and the state of t from cp. The next call to
+// Train continues with the epoch following cp.Epoch
+func (t *BatchTrainer) Resume(cp *Checkpoint) (*Neural, error) {
+	return t.resume(cp, t.solver)
+}
+
+func (t *BatchTrainer) calculateDeltas(n *Neural, e Examples, wid int) {
+	ws := t.workspaces[wid]
+	ws.load(e)
+	n.forward(ws, len(e))
+	loss := configLoss(n.Config)
+	if t.trackLoss {
+		t.losses[wid] = n.loss(ws, e, loss) * float64(len(e))
 	}
+	n.backward(ws, e, loss)
+	n.gradients(ws, len(e), t.partialDeltas[wid])
 }
 
-func (t *BatchTrainer) update(n *Neural, it int) {
+func (t *BatchTrainer) update(n *Neural, size, it int) error {
+	// Gradients are summed over the batch, and so is the penalty
+	n.regularize(t.accumulatedDeltas, float64(size))
+	n.clip(t.accumulatedDeltas)
 	var idx int
 	for i, l := range n.Layers {
-		iAD := t.accumulatedDeltas[i]
-		for j, n := range l.Neurons {
-			jAD := iAD[j]
-			for k, s := range n.In {
-				update := t.solver.Update(s.Weight,
-					jAD[k],
-					it,
-					idx)
-				s.Weight += update
-				jAD[k] = 0
-				idx++
+		iAD, params := t.accumulatedDeltas[i], l.Params()
+		for k, w := range params {
+			params[k] += t.solver.Update(w, iAD[k], it, idx)
+			n.decay(i, k, w)
+			if err := n.finite(i, k, it); err != nil {
+				return err
 			}
+			iAD[k] = 0
+			idx++
 		}
 	}
+	return nil
 }
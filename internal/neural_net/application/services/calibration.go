@@ -0,0 +1,188 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"main/internal/neural_net/application/services/layer/neuron/synapse/activation"
+	"main/internal/neural_net/domain/entities"
+	nnErrors "main/internal/neural_net/domain/errors"
+	"math"
+	"sort"
+)
+
+// Calibration maps the output of a ModeBinary network to a calibrated
+// probability
+type Calibration struct {
+	Method entities.CalibrationType
+	// CalibrationPlatt: the probability is sigmoid(A*logit(output)+B)
+	A, B float64 `json:",omitempty"`
+	// CalibrationIsotonic: the probability interpolates linearly between the
+	// points (X, Y), X ascending, and is constant beyond them
+	X, Y []float64 `json:",omitempty"`
+}
+
+// Apply returns the calibrated probability of output p
+func (c *Calibration) Apply(p float64) float64 {
+	switch c.Method {
+	case entities.CalibrationPlatt:
+		return activation.Logistic(c.A*logit(p)+c.B, 1)
+	case entities.CalibrationIsotonic:
+		i := sort.SearchFloat64s(c.X, p)
+		switch {
+		case i == 0:
+			return c.Y[0]
+		case i == len(c.X):
+			return c.Y[len(c.Y)-1]
+		}
+		w := (p - c.X[i-1]) / (c.X[i] - c.X[i-1])
+		return c.Y[i-1] + w*(c.Y[i]-c.Y[i-1])
+	}
+	return p
+}
+
+// Calibrate fits a calibration of the output of n to the validation examples
+// with the given method, which Predict applies from then on. CalibrationNone
+// removes it. Only ModeBinary networks are calibrated
+func (n *Neural) Calibrate(validation Examples, method entities.CalibrationType) error {
+	if n.Config.Mode != entities.ModeBinary {
+		return fmt.Errorf("%w: calibration needs ModeBinary, got mode %d", nnErrors.ErrUnsupportedMode, n.Config.Mode)
+	}
+	if method != entities.CalibrationNone && len(validation) == 0 {
+		return errors.New("calibration needs validation examples")
+	}
+	n.Calibration = nil
+	if method == entities.CalibrationNone {
+		return nil
+	}
+	scores, labels := make([]float64, len(validation)), make([]float64, len(validation))
+	for i, e := range validation {
+		scores[i], labels[i] = n.Predict(e.Input)[0], e.Response[0]
+	}
+	switch method {
+	case entities.CalibrationPlatt:
+		n.Calibration = fitPlatt(scores, labels)
+	case entities.CalibrationIsotonic:
+		n.Calibration = fitIsotonic(scores, labels)
+	default:
+		return fmt.Errorf("unknown calibration %d", method)
+	}
+	return nil
+}
+
+// calibrate applies the calibration of n to its output
+func (n *Neural) calibrate(out []float64) {
+	if n.Calibration != nil {
+		out[0] = n.Calibration.Apply(out[0])
+	}
+}
+
+// logit is the inverse of the sigmoid, its argument clipped away from 0 and 1
+func logit(p float64) float64 {
+	const eps = 1e-15
+	p = math.Min(math.Max(p, eps), 1-eps)
+	return math.Log(p / (1 - p))
+}
+
+// softplus is log(1+e^x)
+func softplus(x float64) float64 {
+	return math.Max(x, 0) + math.Log1p(math.Exp(-math.Abs(x)))
+}
+
+// fitPlatt minimizes the cross-entropy of sigmoid(A*logit(score)+B) by
+// Newton's method, with the regularized targets of Platt (1999)
+func fitPlatt(scores, labels []float64) *Calibration {
+	var positives, negatives float64
+	for _, y := range labels {
+		if y >= 0.5 {
+			positives++
+		} else {
+			negatives++
+		}
+	}
+	hi, lo := (positives+1)/(positives+2), 1/(negatives+2)
+	f, t := make([]float64, len(scores)), make([]float64, len(scores))
+	for i, s := range scores {
+		f[i], t[i] = logit(s), lo
+		if labels[i] >= 0.5 {
+			t[i] = hi
+		}
+	}
+	loss := func(a, b float64) (sum float64) {
+		for i := range f {
+			z := a*f[i] + b
+			sum += t[i]*softplus(-z) + (1-t[i])*softplus(z)
+		}
+		return sum
+	}
+
+	// Start from the identity, the outputs are already probabilities
+	c := &Calibration{Method: entities.CalibrationPlatt, A: 1}
+	current := loss(c.A, c.B)
+	for it := 0; it < 100; it++ {
+		var ga, gb, haa, hab, hbb float64
+		for i := range f {
+			q := activation.Logistic(c.A*f[i]+c.B, 1)
+			d, w := q-t[i], q*(1-q)
+			ga, gb = ga+d*f[i], gb+d
+			haa, hab, hbb = haa+w*f[i]*f[i], hab+w*f[i], hbb+w
+		}
+		if math.Abs(ga) < 1e-9 && math.Abs(gb) < 1e-9 {
+			break
+		}
+		haa, hbb = haa+1e-12, hbb+1e-12
+		det := haa*hbb - hab*hab
+		da, db := -(hbb*ga-hab*gb)/det, -(haa*gb-hab*ga)/det
+		// Halve the step until the loss decreases
+		step := 1.0
+		for ; step > 1e-10; step /= 2 {
+			if l := loss(c.A+step*da, c.B+step*db); l < current {
+				c.A, c.B, current = c.A+step*da, c.B+step*db, l
+				break
+			}
+		}
+		if step <= 1e-10 {
+			break
+		}
+	}
+	return c
+}
+
+// fitIsotonic fits the non-decreasing step function closest to the labels in
+// the order of the scores by pooling adjacent violators, then joins the ends
+// of its steps
+func fitIsotonic(scores, labels []float64) *Calibration {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return scores[order[a]] < scores[order[b]] })
+
+	// Blocks of the step function, examples of equal scores start in one
+	type block struct {
+		lo, hi, sum, weight float64
+	}
+	var blocks []block
+	for _, i := range order {
+		s := scores[i]
+		if k := len(blocks) - 1; k >= 0 && blocks[k].hi == s {
+			blocks[k].sum += labels[i]
+			blocks[k].weight++
+		} else {
+			blocks = append(blocks, block{lo: s, hi: s, sum: labels[i], weight: 1})
+		}
+		for k := len(blocks) - 1; k > 0 && blocks[k-1].sum/blocks[k-1].weight >= blocks[k].sum/blocks[k].weight; k-- {
+			prev, last := blocks[k-1], blocks[k]
+			blocks[k-1] = block{lo: prev.lo, hi: last.hi, sum: prev.sum + last.sum, weight: prev.weight + last.weight}
+			blocks = blocks[:k]
+		}
+	}
+
+	c := &Calibration{Method: entities.CalibrationIsotonic}
+	for _, b := range blocks {
+		c.X, c.Y = append(c.X, b.lo), append(c.Y, b.sum/b.weight)
+		if b.hi > b.lo {
+			c.X, c.Y = append(c.X, b.hi), append(c.Y, b.sum/b.weight)
+		}
+	}
+	return c
+}
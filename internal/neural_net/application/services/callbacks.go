@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"main/internal/neural_net/application/services/solver"
+	"main/internal/neural_net/domain/entities"
+	"main/internal/neural_net/domain/utils"
+	"main/pkg/logger"
+	"math"
+	"strings"
+	"time"
+)
+
+// Callback is notified by trainers as training progresses. An error
+// returned by any method aborts training and is returned by Train
+type Callback interface {
+	OnEpochStart(p *Progress) error
+	OnBatchEnd(p *Progress) error
+	OnEpochEnd(p *Progress) error
+	// OnTrainEnd is called once training stops without error, p.Result
+	// then tells why
+	OnTrainEnd(p *Progress) error
+}
+
+// CallbackFuncs adapts functions to a Callback, nil ones are skipped
+type CallbackFuncs struct {
+	EpochStart, BatchEnd, EpochEnd, TrainEnd func(p *Progress) error
+}
+
+// OnEpochStart calls EpochStart
+func (c CallbackFuncs) OnEpochStart(p *Progress) error { return call(c.EpochStart, p) }
+
+// OnBatchEnd calls BatchEnd
+func (c CallbackFuncs) OnBatchEnd(p *Progress) error { return call(c.BatchEnd, p) }
+
+// OnEpochEnd calls EpochEnd
+func (c CallbackFuncs) OnEpochEnd(p *Progress) error { return call(c.EpochEnd, p) }
+
+// OnTrainEnd calls TrainEnd
+func (c CallbackFuncs) OnTrainEnd(p *Progress) error { return call(c.TrainEnd, p) }
+
+func call(f func(p *Progress) error, p *Progress) error {
+	if f == nil {
+		return nil
+	}
+	return f(p)
+}
+
+// callbacks are the callbacks registered with a trainer
+type callbacks []Callback
+
+func (c *callbacks) add(cbs []Callback) {
+	*c = append(*c, cbs...)
+}
+
+// withPrinter returns the callbacks followed by one printing to p every
+// verbosity epochs, if verbosity is positive
+func (c callbacks) withPrinter(p *StatsPrinter, verbosity int) []Callback {
+	if verbosity <= 0 {
+		return c
+	}
+	return append(c[:len(c):len(c)], &printerCallback{printer: p, verbosity: verbosity})
+}
+
+// Progress is the state of training passed to callbacks
+type Progress struct {
+	Network *Neural
+	Solver  solver.Solver
+	// Current epoch, counted from 1
+	Epoch int
+	// Index of the last batch within the epoch
+	Batch int
+	// Loss of the training examples, without penalties: of the last batch
+	// in OnBatchEnd, averaged over the batches of the epoch in OnEpochEnd
+	Loss float64
+	// Time since Train was called
+	Elapsed time.Duration
+	// Outcome of training so far
+	Result *Result
+
+	start      time.Time
+	validation Examples
+	frequency  int
+	metrics    map[entities.MetricType]float64
+	losses     float64
+	stop       bool
+}
+
+// Metric returns metric m for the validation examples given to Train, or
+// NaN if there are none or the epoch is not one evaluating them. It is
+// computed at most once per state of the network
+func (p *Progress) Metric(m entities.MetricType) float64 {
+	if !p.validates() {
+		return math.NaN()
+	}
+	if v, ok := p.metrics[m]; ok {
+		return v
+	}
+	v := Evaluate(p.Network, p.validation, m)
+	p.metrics[m] = v
+	return v
+}
+
+// validates reports whether the validation examples are evaluated this epoch
+func (p *Progress) validates() bool {
+	return len(p.validation) > 0 && p.Epoch%p.frequency == 0
+}
+
+// Stop makes training stop after the current batch
+func (p *Progress) Stop() {
+	p.stop = true
+}
+
+// session is the bookkeeping of one call to Train, shared by the trainers
+type session struct {
+	Progress
+	ctx       context.Context
+	callbacks []Callback
+	stopper   *stopper
+}
+
+func newSession(ctx context.Context, n *Neural, s solver.Solver, validation Examples, frequency int,
+	es *entities.EarlyStopping, callbacks []Callback) (*session, error) {
+	stopper, err := newStopper(es, validation)
+	if err != nil {
+		return nil, err
+	}
+	ss := &session{
+		Progress: Progress{
+			Network:    n,
+			Solver:     s,
+			Result:     &Result{},
+			start:      time.Now(),
+			validation: validation,
+			frequency:  frequency,
+			metrics:    map[entities.MetricType]float64{},
+		},
+		ctx:       ctx,
+		callbacks: callbacks,
+		stopper:   stopper,
+	}
+	stopper.init(ss.Result)
+	return ss, nil
+}
+
+// tracksLoss reports whether the trainer must compute the loss of batches,
+// which only the StatsPrinter does without
+func (s *session) tracksLoss() bool {
+	for _, c := range s.callbacks {
+		if _, ok := c.(*printerCallback); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *session) notify(f func(c Callback, p *Progress) error) error {
+	s.Elapsed = time.Since(s.start)
+	for _, c := range s.callbacks {
+		if err := f(c, &s.Progress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *session) epochStart(epoch int) error {
+	s.Epoch, s.Batch, s.losses = epoch, 0, 0
+	s.reset()
+	return s.notify(Callback.OnEpochStart)
+}
+
+func (s *session) batchEnd(batch int, loss float64) error {
+	s.Batch, s.Loss = batch, loss
+	s.losses += loss
+	s.reset()
+	return s.notify(Callback.OnBatchEnd)
+}
+
+// epochEnd runs the end of epoch hooks: learning rate schedules, callbacks
+// and early stopping
+func (s *session) epochEnd() error {
+	s.Loss = s.losses / float64(s.Batch+1)
+	s.Result.Epochs = s.Epoch
+	if scheduled, ok := s.Solver.(*solver.Scheduled); ok && s.validates() {
+		if o, ok := scheduled.Schedule().(solver.Observer); ok {
+			o.Observe(s.Metric(entities.MetricLoss))
+		}
+	}
+	if err := s.notify(Callback.OnEpochEnd); err != nil {
+		return err
+	}
+	if s.validates() && s.stopper.stop(&s.Progress) {
+		s.Result.Stop = entities.StopEarly
+	}
+	return nil
+}
+
+// stopped reports whether training should stop, marking why
+func (s *session) stopped() bool {
+	if s.stop && s.Result.Stop == entities.StopCompleted {
+		s.Result.Stop = entities.StopCallback
+	}
+	return s.stop || s.Result.Stop != entities.StopCompleted
+}
+
+// canceled returns the error of the context once it is done, marking the
+// stop. Trainers check it between batches, so n is never left mid-update
+func (s *session) canceled() error {
+	if err := s.ctx.Err(); err != nil {
+		s.Result.Stop = entities.StopCanceled
+		return err
+	}
+	return nil
+}
+
+// end finishes training after epochs epochs
+func (s *session) end(epochs int) (*Result, error) {
+	s.Result.Epochs = epochs
+	s.stopper.restore(s.Network)
+	s.reset()
+	return s.Result, s.notify(Callback.OnTrainEnd)
+}
+
+// fail aborts training with err after epochs epochs
+func (s *session) fail(epochs int, err error) (*Result, error) {
+	s.Result.Epochs = epochs
+	return s.Result, err
+}
+
+// reset drops the cached metrics after the network changed
+func (s *session) reset() {
+	for m := range s.metrics {
+		delete(s.metrics, m)
+	}
+}
+
+// NewLoggingCallback returns a Callback logging the training loss, the
+// given metrics of the validation examples and the learning rate to l every
+// epochs epochs
+func NewLoggingCallback(l logger.Logger, epochs int, metrics ...entities.MetricType) Callback {
+	epochs = utils.Iparam(epochs, 1)
+	return CallbackFuncs{
+		EpochEnd: func(p *Progress) error {
+			if p.Epoch%epochs != 0 {
+				return nil
+			}
+			var b strings.Builder
+			fmt.Fprintf(&b, "epoch %d (%s): loss %.4f", p.Epoch, p.Elapsed, p.Loss)
+			for _, m := range metrics {
+				fmt.Fprintf(&b, ", validation %s %.4f", m, p.Metric(m))
+			}
+			if s, ok := p.Solver.(solver.Tunable); ok {
+				fmt.Fprintf(&b, ", lr %.3g", s.LearningRate())
+			}
+			l.Info(b.String())
+			return nil
+		},
+		TrainEnd: func(p *Progress) error {
+			l.Infof("training ended after %d epochs: %s", p.Result.Epochs, p.Result.Stop)
+			return nil
+		},
+	}
+}
+
+// Checkpointer is a trainer able to snapshot a training job
+type Checkpointer interface {
+	Checkpoint(n *Neural) (*Checkpoint, error)
+}
+
+// NewCheckpointCallback returns a Callback passing a checkpoint of t to
+// save every epochs epochs and when training ends
+func NewCheckpointCallback(t Checkpointer, epochs int, save func(cp *Checkpoint) error) Callback {
+	epochs = utils.Iparam(epochs, 1)
+	checkpoint := func(p *Progress) error {
+		cp, err := t.Checkpoint(p.Network)
+		if err != nil {
+			return err
+		}
+		return save(cp)
+	}
+	return CallbackFuncs{
+		EpochEnd: func(p *Progress) error {
+			if p.Epoch%epochs != 0 {
+				return nil
+			}
+			return checkpoint(p)
+		},
+		TrainEnd: checkpoint,
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"main/internal/neural_net/application/services/solver"
+	"math/rand"
+)
+
+// Checkpoint is a snapshot of a training job, from which training resumes
+// exactly where it stopped
+type Checkpoint struct {
+	Dump *Dump
+	// State of the solver, see solver.Stateful
+	Solver json.RawMessage
+	// Number of epochs completed
+	Epoch int
+	// Seed of the shuffling and dropout of the trainer
+	Seed int64
+}
+
+// progress is the position of a trainer in a training job. Every epoch
+// reseeds the random sources it uses, so that it draws the same numbers
+// whether or not training was resumed in between
+type progress struct {
+	epoch int
+	seed  int64
+	// Seed set with WithSeed, drawn at random for every job when unset
+	fixedSeed int64
+	resumed   bool
+	rng       *rand.Rand
+}
+
+// start prepares the training of n, unless a resumed job is pending
+func (p *progress) start(n *Neural, s solver.Solver) {
+	if p.rng == nil {
+		p.rng = rand.New(rand.NewSource(0))
+	}
+	if p.resumed {
+		p.resumed = false
+		return
+	}
+	p.epoch, p.seed = 0, p.fixedSeed
+	for p.seed == 0 {
+		p.seed = rand.Int63()
+	}
+	s.Init(n.NumWeights())
+}
+
+// Seed returns the seed of the shuffling and dropout of the last training
+// job, which WithSeed reproduces
+func (p *progress) Seed() int64 {
+	return p.seed
+}
+
+// reseed seeds rng for the given stream of epoch
+func (p *progress) reseed(rng *rand.Rand, epoch, stream int) {
+	rng.Seed(p.seed + int64(epoch)*1_000_003 + int64(stream))
+}
+
+func (p *progress) checkpoint(n *Neural, s solver.Solver) (*Checkpoint, error) {
+	stateful, ok := s.(solver.Stateful)
+	if !ok {
+		return nil, fmt.Errorf("solver %T has no state to save", s)
+	}
+	state, err := stateful.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return &Checkpoint{
+		Dump:   n.Dump(),
+		Solver: state,
+		Epoch:  p.epoch,
+		Seed:   p.seed,
+	}, nil
+}
+
+func (p *progress) resume(cp *Checkpoint, s solver.Solver) (*Neural, error) {
+	if err := CheckConfig(cp.Dump.Config); err != nil {
+		return nil, err
+	}
+	stateful, ok := s.(solver.Stateful)
+	if !ok {
+		return nil, fmt.Errorf("solver %T has no state to restore", s)
+	}
+	if err := stateful.UnmarshalJSON(cp.Solver); err != nil {
+		return nil, err
+	}
+	p.epoch, p.seed, p.resumed = cp.Epoch, cp.Seed, true
+	return FromDump(cp.Dump), nil
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"fmt"
+	nnErrors "main/internal/neural_net/domain/errors"
+	"math"
+)
+
+// clip clips grads, which are shaped like the parameters of n, by value and
+// then by global norm
+func (n *Neural) clip(grads [][]float64) {
+	c := n.Config
+	if c.ClipValue > 0 {
+		for _, g := range grads {
+			for k, v := range g {
+				g[k] = math.Max(-c.ClipValue, math.Min(c.ClipValue, v))
+			}
+		}
+	}
+	if c.ClipNorm > 0 {
+		var sum float64
+		for _, g := range grads {
+			for _, v := range g {
+				sum += v * v
+			}
+		}
+		if norm := math.Sqrt(sum); norm > c.ClipNorm {
+			scale := c.ClipNorm / norm
+			for _, g := range grads {
+				for k := range g {
+					g[k] *= scale
+				}
+			}
+		}
+	}
+}
+
+// finite returns ErrDiverged if parameter k of layer i is NaN or Inf
+func (n *Neural) finite(i, k, it int) error {
+	if v := n.Layers[i].Params()[k]; math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("%w: layer %d parameter %d is %v at epoch %d", nnErrors.ErrDiverged, i, k, v, it)
+	}
+	return nil
+}
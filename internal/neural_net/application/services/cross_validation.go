@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"main/internal/neural_net/domain/entities"
+	"main/internal/neural_net/domain/utils"
+	"math"
+	"math/rand"
+)
+
+// Fold is one split of examples into training and validation examples
+type Fold struct {
+	Train, Validation Examples
+}
+
+// KFold splits e into k folds of sizes differing by at most one, each
+// validating on one part and training on the others. The parts are drawn at
+// random from rng, or are contiguous when rng is nil
+func (e Examples) KFold(k int, rng *rand.Rand) ([]Fold, error) {
+	if err := checkFolds(k, len(e)); err != nil {
+		return nil, err
+	}
+	order := make([]int, len(e))
+	for i := range order {
+		order[i] = i
+	}
+	if rng != nil {
+		rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+	}
+	assigned := make([]int, len(e))
+	for j, i := range order {
+		assigned[i] = j * k / len(e)
+	}
+	return e.folds(k, assigned), nil
+}
+
+// StratifiedKFold is KFold keeping the proportion of every class about the
+// same in all folds. The class of an example is its largest response, or
+// whether its response is at least 0.5 for a single output (ModeBinary)
+func (e Examples) StratifiedKFold(k int, rng *rand.Rand) ([]Fold, error) {
+	if err := checkFolds(k, len(e)); err != nil {
+		return nil, err
+	}
+	classes := map[int][]int{}
+	var order []int
+	for i, ex := range e {
+		c := class(ex.Response)
+		if _, ok := classes[c]; !ok {
+			order = append(order, c)
+		}
+		classes[c] = append(classes[c], i)
+	}
+	// Dealing the examples of every class in turn, the folds take the
+	// remainders of the classes one after the other
+	assigned := make([]int, len(e))
+	var next int
+	for _, c := range order {
+		members := classes[c]
+		if rng != nil {
+			rng.Shuffle(len(members), func(i, j int) { members[i], members[j] = members[j], members[i] })
+		}
+		for _, i := range members {
+			assigned[i] = next % k
+			next++
+		}
+	}
+	return e.folds(k, assigned), nil
+}
+
+// TimeSeriesSplit splits e, ordered in time, into folds validating on
+// consecutive parts of its end, each training on the examples before it:
+// all of them (expanding window), or the last window ones when window is
+// positive (walk-forward)
+func (e Examples) TimeSeriesSplit(folds, window int) ([]Fold, error) {
+	if folds < 1 || len(e) < folds+1 {
+		return nil, fmt.Errorf("cannot split %d examples into %d time series folds", len(e), folds)
+	}
+	size := len(e) / (folds + 1)
+	res := make([]Fold, folds)
+	for f := range res {
+		lo := len(e) - (folds-f)*size
+		start := 0
+		if window > 0 && lo > window {
+			start = lo - window
+		}
+		res[f] = Fold{Train: e[start:lo], Validation: e[lo : lo+size]}
+	}
+	return res, nil
+}
+
+func checkFolds(k, examples int) error {
+	if k < 2 || k > examples {
+		return fmt.Errorf("cannot split %d examples into %d folds", examples, k)
+	}
+	return nil
+}
+
+// class is the class of an example with the given response
+func class(response []float64) int {
+	if len(response) == 1 {
+		if response[0] >= 0.5 {
+			return 1
+		}
+		return 0
+	}
+	return utils.ArgMax(response)
+}
+
+// folds returns the k folds of e given the fold each example is assigned
+// to, keeping the examples in order
+func (e Examples) folds(k int, assigned []int) []Fold {
+	res := make([]Fold, k)
+	for i, ex := range e {
+		for f := range res {
+			if assigned[i] == f {
+				res[f].Validation = append(res[f].Validation, ex)
+			} else {
+				res[f].Train = append(res[f].Train, ex)
+			}
+		}
+	}
+	return res
+}
+
+// FoldResult is the outcome of training on one fold
+type FoldResult struct {
+	// Training result
+	*Result
+	// Metrics of the validation examples of the fold
+	Metrics map[entities.MetricType]float64
+}
+
+// CVResult is the outcome of cross-validation
+type CVResult struct {
+	Folds []FoldResult
+	// Mean and (population) standard deviation of every metric over folds
+	Mean, Std map[entities.MetricType]float64
+}
+
+// CrossValidateFolds trains a fresh network built from c on every fold with
+// a trainer from newTrainer, passing it the validation examples of the fold,
+// and evaluates the given metrics on them (the loss by default)
+func CrossValidateFolds(ctx context.Context, c *entities.Config, newTrainer func() Trainer, folds []Fold,
+	iterations int, metrics ...entities.MetricType) (*CVResult, error) {
+	if len(metrics) == 0 {
+		metrics = []entities.MetricType{entities.MetricLoss}
+	}
+	res := &CVResult{
+		Folds: make([]FoldResult, len(folds)),
+		Mean:  map[entities.MetricType]float64{},
+		Std:   map[entities.MetricType]float64{},
+	}
+	for f, fold := range folds {
+		// NewNeural fills in the config, every fold gets a copy of its own
+		config := *c
+		n := NewNeural(&config)
+		result, err := newTrainer().Train(ctx, n, fold.Train, fold.Validation, iterations)
+		if err != nil {
+			return nil, fmt.Errorf("fold %d: %w", f, err)
+		}
+		res.Folds[f] = FoldResult{Result: result, Metrics: map[entities.MetricType]float64{}}
+		for _, m := range metrics {
+			v := Evaluate(n, fold.Validation, m)
+			res.Folds[f].Metrics[m] = v
+			res.Mean[m] += v / float64(len(folds))
+		}
+	}
+	for _, m := range metrics {
+		for _, fr := range res.Folds {
+			d := fr.Metrics[m] - res.Mean[m]
+			res.Std[m] += d * d / float64(len(folds))
+		}
+		res.Std[m] = math.Sqrt(res.Std[m])
+	}
+	return res, nil
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"errors"
+	"main/internal/neural_net/application/services/layer"
+	"main/internal/neural_net/domain/entities"
+	"main/internal/neural_net/domain/utils"
+	"math"
+)
+
+// Result summarizes a training run
+type Result struct {
+	// Number of epochs completed
+	Epochs int
+	// Why training stopped
+	Stop entities.StopReason
+	// With early stopping: the best value of the monitored metric and the
+	// epoch it was reached at, whose weights n holds after training
+	Best      float64
+	BestEpoch int
+}
+
+// improves reports whether v of metric m is better than best by more than
+// minDelta
+func improves(m entities.MetricType, v, best, minDelta float64) bool {
+	if m.HigherIsBetter() {
+		return v > best+minDelta
+	}
+	return v < best-minDelta
+}
+
+// stopper implements early stopping, keeping a copy of the best layers seen
+type stopper struct {
+	config *entities.EarlyStopping
+	wait   int
+	best   []*layer.Layer
+}
+
+func newStopper(config *entities.EarlyStopping, validation Examples) (*stopper, error) {
+	if config == nil {
+		return nil, nil
+	}
+	if len(validation) == 0 {
+		return nil, errors.New("early stopping needs validation examples")
+	}
+	return &stopper{config: config}, nil
+}
+
+// init prepares r for monitoring
+func (s *stopper) init(r *Result) {
+	if s == nil {
+		return
+	}
+	r.Best = math.Inf(1)
+	if s.config.Monitor.HigherIsBetter() {
+		r.Best = math.Inf(-1)
+	}
+}
+
+// stop evaluates the network at the end of an epoch and reports whether
+// training should stop
+func (s *stopper) stop(p *Progress) bool {
+	if s == nil {
+		return false
+	}
+	n, r := p.Network, p.Result
+	v := p.Metric(s.config.Monitor)
+	if !improves(s.config.Monitor, v, r.Best, s.config.MinDelta) {
+		s.wait++
+		return s.wait >= utils.Iparam(s.config.Patience, 10)
+	}
+	r.Best, r.BestEpoch, s.wait = v, p.Epoch, 0
+	if s.best == nil {
+		s.best = make([]*layer.Layer, len(n.Layers))
+		for i, l := range n.Layers {
+			s.best[i] = l.Clone()
+		}
+		return false
+	}
+	copyLayers(s.best, n.Layers)
+	return false
+}
+
+// restore sets n back to the best layers seen
+func (s *stopper) restore(n *Neural) {
+	if s == nil || s.best == nil {
+		return
+	}
+	copyLayers(n.Layers, s.best)
+}
+
+// copyLayers copies the parameters and running statistics of src to dst
+func copyLayers(dst, src []*layer.Layer) {
+	for i, l := range src {
+		copy(dst[i].Params(), l.Params())
+		copy(dst[i].Mean, l.Mean)
+		copy(dst[i].Var, l.Var)
+	}
+}
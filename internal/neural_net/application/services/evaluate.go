@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"main/internal/neural_net/application/services/metrics"
+	"main/internal/neural_net/domain/entities"
+	nnErrors "main/internal/neural_net/domain/errors"
+)
+
+// Evaluate computes metric for n on examples
+func Evaluate(n *Neural, examples Examples, metric entities.MetricType) float64 {
+	if metric == entities.MetricLoss {
+		return CrossValidate(n, examples)
+	}
+	estimate, ideal := predictAll(n, examples)
+	return metrics.Evaluate(metric, n.Decision(), estimate, ideal)
+}
+
+// Decision returns how n decides classes from its outputs
+func (n *Neural) Decision() metrics.Decision {
+	return metrics.Decision{Mode: n.Config.Mode, Thresholds: n.Thresholds}
+}
+
+// Classify returns the classes n decides for input
+func (n *Neural) Classify(input []float64) []bool {
+	return n.Decision().Decide(n.Predict(input))
+}
+
+// FitThresholds sets the threshold of every label of n to the one maximizing
+// its F1 score on the validation examples. Only ModeBinary and
+// ModeMultiLabel outputs are decided by thresholds. They apply to calibrated
+// outputs: calibrate first
+func (n *Neural) FitThresholds(validation Examples) error {
+	if n.Config.Mode != entities.ModeBinary && n.Config.Mode != entities.ModeMultiLabel {
+		return fmt.Errorf("%w: thresholds need ModeBinary or ModeMultiLabel, got mode %d",
+			nnErrors.ErrUnsupportedMode, n.Config.Mode)
+	}
+	if len(validation) == 0 {
+		return errors.New("thresholds need validation examples")
+	}
+	n.Thresholds = metrics.Thresholds(predictAll(n, validation))
+	return nil
+}
+
+// Accuracy is the fraction of examples whose classes n decides right, see
+// MetricAccuracy
+func Accuracy(n *Neural, examples Examples) float64 {
+	return Evaluate(n, examples, entities.MetricAccuracy)
+}
+
+// ConfusionMatrix counts the examples of every true class (rows) by the class
+// n decides (columns)
+func ConfusionMatrix(n *Neural, examples Examples) [][]int {
+	estimate, ideal := predictAll(n, examples)
+	return metrics.ConfusionMatrix(n.Decision(), estimate, ideal)
+}
+
+// predictAll returns the predictions of n and the responses of examples
+func predictAll(n *Neural, examples Examples) ([][]float64, [][]float64) {
+	estimate, ideal := make([][]float64, len(examples)), make([][]float64, len(examples))
+	for i, e := range examples {
+		estimate[i] = n.Predict(e.Input)
+		ideal[i] = e.Response
+	}
+	return estimate, ideal
+}
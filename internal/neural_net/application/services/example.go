@@ -11,19 +11,37 @@ type Example struct {
 // Examples is a set of input-output pairs
 type Examples []Example
 
-// Shuffle shuffles slice in-place
+// Shuffle shuffles slice in-place, drawing from the global random source
 func (e Examples) Shuffle() {
+	e.shuffle(rand.Intn)
+}
+
+// ShuffleWith shuffles slice in-place, drawing from rng
+func (e Examples) ShuffleWith(rng *rand.Rand) {
+	e.shuffle(rng.Intn)
+}
+
+func (e Examples) shuffle(intn func(int) int) {
 	for i := range e {
-		j := rand.Intn(i + 1)
+		j := intn(i + 1)
 		e[i], e[j] = e[j], e[i]
 	}
 }
 
 // Split assigns each element to two new slices
-// according to probability p
+// according to probability p, drawing from the global random source
 func (e Examples) Split(p float64) (first, second Examples) {
+	return e.split(p, rand.Float64)
+}
+
+// SplitWith is Split drawing from rng
+func (e Examples) SplitWith(p float64, rng *rand.Rand) (first, second Examples) {
+	return e.split(p, rng.Float64)
+}
+
+func (e Examples) split(p float64, draw func() float64) (first, second Examples) {
 	for i := 0; i < len(e); i++ {
-		if p > rand.Float64() {
+		if p > draw() {
 			first = append(first, e[i])
 		} else {
 			second = append(second, e[i])
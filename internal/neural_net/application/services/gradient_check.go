@@ -0,0 +1,81 @@
+package services
+
+import (
+	"main/internal/neural_net/domain/utils"
+	"math"
+)
+
+// GradientError compares the derivatives of the loss with respect to a
+// parameter computed by backpropagation and by finite differences
+type GradientError struct {
+	// Layer of the parameter and its index in the layer's Params
+	Layer, Index      int
+	Analytic, Numeric float64
+	// |Analytic-Numeric| / max(|Analytic|, |Numeric|), the denominator at
+	// least 1e-6 so that vanishing derivatives compare absolutely
+	Relative float64
+}
+
+// GradientCheck holds the comparison of every parameter of a network
+type GradientCheck []GradientError
+
+// Worst returns the comparison with the largest relative error
+func (c GradientCheck) Worst() GradientError {
+	var worst GradientError
+	for _, e := range c {
+		if e.Relative >= worst.Relative {
+			worst = e
+		}
+	}
+	return worst
+}
+
+// CheckGradients compares the gradient of the mean loss of examples,
+// penalties included, that trainers backpropagate through n with central
+// differences of step h (default 1e-5), for every parameter of n. A nil loss
+// selects the loss of n. The network is in training mode, as trainers see
+// it: the examples form a single batch and the dropout masks are drawn once.
+// The parameters of n are left unchanged
+func CheckGradients(n *Neural, loss Loss, examples Examples, h float64) GradientCheck {
+	if loss == nil {
+		loss = configLoss(n.Config)
+	}
+	h = utils.Fparam(h, 1e-5)
+	rows := len(examples)
+	ws := newTrainingWorkspace(n, rows)
+	objective := func() float64 {
+		ws.rng.Seed(0)
+		ws.load(examples)
+		n.forward(ws, rows)
+		return n.loss(ws, examples, loss) + n.penalty()
+	}
+
+	objective()
+	n.backward(ws, examples, loss)
+	grads := n.newGradients()
+	n.gradients(ws, rows, grads)
+	n.regularize(grads, float64(rows))
+
+	var check GradientCheck
+	for i, l := range n.Layers {
+		params := l.Params()
+		for k, w := range params {
+			params[k] = w + h
+			up := objective()
+			params[k] = w - h
+			down := objective()
+			params[k] = w
+
+			analytic, numeric := grads[i][k]/float64(rows), (up-down)/(2*h)
+			scale := math.Max(math.Max(math.Abs(analytic), math.Abs(numeric)), 1e-6)
+			check = append(check, GradientError{
+				Layer:    i,
+				Index:    k,
+				Analytic: analytic,
+				Numeric:  numeric,
+				Relative: math.Abs(analytic-numeric) / scale,
+			})
+		}
+	}
+	return check
+}
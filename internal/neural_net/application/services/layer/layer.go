@@ -2,81 +2,284 @@ package layer
 
 import (
 	"fmt"
-	"main/internal/neural_net/application/services/layer/neuron"
-	"main/internal/neural_net/application/services/layer/neuron/synapse"
 	"main/internal/neural_net/domain/entities"
+	"main/internal/neural_net/domain/ports"
 	"main/internal/neural_net/domain/utils"
 )
 
-// Layer is a set of neurons and corresponding activation
+// Layer is a fully connected layer. The incoming weights of its neurons are
+// stored as a dense, row-major matrix with one row per neuron; when the layer
+// has a bias, the last column of each row holds the bias weight
 type Layer struct {
-	Neurons []*neuron.Neuron
 	A       entities.ActivationType
-}
+	Inputs  int
+	Outputs int
+	Bias    bool
+	Dropout float64
+	Norm    entities.NormType
+	W       []float64
+	// Scale and shift of the normalized sums, one per neuron
+	Gamma, Beta []float64
+	// Running mean and variance of the sums, tracked by batch normalization
+	Mean, Var []float64
 
-// NewLayer creates a new layer with n nodes
-func NewLayer(n int, activation entities.ActivationType) *Layer {
-	neurons := make([]*neuron.Neuron, n)
+	params []float64
+	act    ports.Differentiable
+}
 
-	for i := 0; i < n; i++ {
-		act := activation
-		if activation == entities.ActivationSoftmax {
-			act = entities.ActivationLinear
+// NewLayer creates a new layer with n nodes, each connected to inputs values,
+// given its resolved configuration. It panics if the configuration names an
+// activation that was never registered
+func NewLayer(inputs, n int, c entities.LayerConfig) *Layer {
+	l := &Layer{
+		A:       c.Activation,
+		Inputs:  inputs,
+		Outputs: n,
+		Bias:    c.Bias != nil && *c.Bias,
+		Dropout: c.Dropout,
+		Norm:    c.Norm,
+		act:     utils.GetActivation(c.Activation, c.ActivationAlpha),
+	}
+	if c.ActivationName != "" {
+		act, err := utils.RegisteredActivation(c.ActivationName, c.ActivationAlpha)
+		if err != nil {
+			panic(err)
+		}
+		l.act = act
+	}
+	size := n * l.Stride()
+	if l.Norm != entities.NormNone {
+		size += 2 * n
+	}
+	l.alias(make([]float64, size))
+	for j := range l.Gamma {
+		l.Gamma[j] = 1
+	}
+	if l.Norm == entities.NormBatch {
+		l.Mean = make([]float64, n)
+		l.Var = make([]float64, n)
+		for j := range l.Var {
+			l.Var[j] = 1
 		}
-		neurons[i] = neuron.NewNeuron(act)
 	}
-	return &Layer{
-		Neurons: neurons,
-		A:       activation,
+	return l
+}
+
+// alias makes W, Gamma and Beta views of params
+func (l *Layer) alias(params []float64) {
+	l.params = params
+	size := l.Outputs * l.Stride()
+	l.W = params[:size:size]
+	if l.Norm != entities.NormNone {
+		l.Gamma = params[size : size+l.Outputs : size+l.Outputs]
+		l.Beta = params[size+l.Outputs:]
+	}
+}
+
+// Clone returns a copy of l that shares no parameters with it
+func (l *Layer) Clone() *Layer {
+	c := *l
+	c.alias(append([]float64(nil), l.params...))
+	c.Mean = append([]float64(nil), l.Mean...)
+	c.Var = append([]float64(nil), l.Var...)
+	return &c
+}
+
+// Params returns the trainable parameters of l: W followed by Gamma and Beta.
+// Gradients and solver indices follow the same order
+func (l *Layer) Params() []float64 {
+	return l.params
+}
+
+// Stride is the length of a weight row
+func (l *Layer) Stride() int {
+	if l.Bias {
+		return l.Inputs + 1
+	}
+	return l.Inputs
+}
+
+// Row returns the incoming weights of neuron j
+func (l *Layer) Row(j int) []float64 {
+	s := l.Stride()
+	return l.W[j*s : (j+1)*s]
+}
+
+// IsBias reports whether W[k] is a bias weight
+func (l *Layer) IsBias(k int) bool {
+	return l.Bias && k%l.Stride() == l.Inputs
+}
+
+// Penalized reports whether Params()[k] is subject to weight penalties and
+// decay, which spare biases and normalization parameters
+func (l *Layer) Penalized(k int) bool {
+	return k < len(l.W) && !l.IsBias(k)
+}
+
+// Activation returns the activation applied by every neuron of l
+func (l *Layer) Activation() ports.Differentiable {
+	return l.act
+}
+
+// Buffers hold the values of a pass through a layer for a batch of rows,
+// each stored row-major with Outputs columns. Mask and Dropped are only
+// allocated for layers with dropout, the normalization buffers only for
+// normalized layers
+type Buffers struct {
+	Sum     []float64
+	Out     []float64
+	Delta   []float64
+	Mask    []float64
+	Dropped []float64
+
+	// Normalized sums, before scale and shift
+	Norm []float64
+	// Inverse standard deviations: one per row for layer normalization,
+	// one per neuron for batch normalization
+	InvStd []float64
+	// Statistics of the last training batch (batch normalization)
+	BatchMean, BatchVar []float64
+	// Gradients of Gamma and Beta computed by Denormalize
+	DGamma, DBeta []float64
+
+	rows int
+}
+
+// NewBuffers allocates buffers for up to rows rows
+func (l *Layer) NewBuffers(rows int) *Buffers {
+	b := &Buffers{
+		Sum:   make([]float64, rows*l.Outputs),
+		Out:   make([]float64, rows*l.Outputs),
+		Delta: make([]float64, rows*l.Outputs),
+	}
+	if l.Dropout > 0 {
+		b.Mask = make([]float64, rows*l.Outputs)
+		b.Dropped = make([]float64, rows*l.Outputs)
 	}
+	switch l.Norm {
+	case entities.NormLayer:
+		b.InvStd = make([]float64, rows)
+	case entities.NormBatch:
+		b.InvStd = make([]float64, l.Outputs)
+		b.BatchMean = make([]float64, l.Outputs)
+		b.BatchVar = make([]float64, l.Outputs)
+	}
+	if l.Norm != entities.NormNone {
+		b.Norm = make([]float64, rows*l.Outputs)
+		b.DGamma = make([]float64, l.Outputs)
+		b.DBeta = make([]float64, l.Outputs)
+	}
+	return b
 }
 
-func (l *Layer) Fire() {
-	for _, n := range l.Neurons {
-		n.Fire()
+// Forward computes the activations of rows inputs stored row-major in in.
+// Batch normalization uses the statistics of the rows when training and the
+// running statistics otherwise
+func (l *Layer) Forward(in []float64, b *Buffers, rows int, train bool) {
+	b.rows = rows
+	s := l.Stride()
+	for r := 0; r < rows; r++ {
+		x := in[r*l.Inputs : (r+1)*l.Inputs]
+		sum := b.Sum[r*l.Outputs : (r+1)*l.Outputs]
+		for j := range sum {
+			w := l.W[j*s : (j+1)*s]
+			v := utils.Dot(x, w)
+			if l.Bias {
+				v += w[l.Inputs]
+			}
+			sum[j] = v
+		}
+	}
+	if l.Norm != entities.NormNone {
+		l.normalize(b, rows, train)
+	}
+	for i := 0; i < rows*l.Outputs; i++ {
+		b.Out[i] = l.act.F(b.Sum[i])
 	}
 	if l.A == entities.ActivationSoftmax {
-		outs := make([]float64, len(l.Neurons))
-		for i, neuron := range l.Neurons {
-			outs[i] = neuron.Value
+		for r := 0; r < rows; r++ {
+			out := b.Out[r*l.Outputs : (r+1)*l.Outputs]
+			utils.SoftmaxTo(out, out)
 		}
-		sm := utils.Softmax(outs)
-		for i, neuron := range l.Neurons {
-			neuron.Value = sm[i]
+	}
+}
+
+// Drop draws a new dropout mask for rows using random, a source of uniform
+// values in [0, 1), and stores the masked outputs in b.Dropped. Kept outputs
+// are scaled by 1/(1-Dropout) so that no scaling is needed at inference
+func (l *Layer) Drop(b *Buffers, rows int, random func() float64) {
+	scale := 1 / (1 - l.Dropout)
+	for i := 0; i < rows*l.Outputs; i++ {
+		b.Mask[i] = 0
+		if random() >= l.Dropout {
+			b.Mask[i] = scale
 		}
+		b.Dropped[i] = b.Out[i] * b.Mask[i]
+	}
+}
+
+// Undrop applies the last dropout mask to the deltas of rows
+func (l *Layer) Undrop(b *Buffers, rows int) {
+	for i := 0; i < rows*l.Outputs; i++ {
+		b.Delta[i] *= b.Mask[i]
+	}
+}
+
+// Derive multiplies the deltas of rows by the activation derivative
+func (l *Layer) Derive(b *Buffers, rows int) {
+	for i := 0; i < rows*l.Outputs; i++ {
+		b.Delta[i] *= l.act.Df(b.Sum[i], b.Out[i])
 	}
 }
 
-// Connect fully connects layer l to next, and initializes each
-// synapse with the given weight function
-func (l *Layer) Connect(next *Layer, weight synapse.WeightInitializer) {
-	for i := range l.Neurons {
-		for j := range next.Neurons {
-			syn := synapse.NewSynapse(weight())
-			l.Neurons[i].Out = append(l.Neurons[i].Out, syn)
-			next.Neurons[j].In = append(next.Neurons[j].In, syn)
+// Backward propagates the deltas of rows to the inputs of l, storing the
+// result row-major in prev (before applying the derivative of the previous
+// layer)
+func (l *Layer) Backward(b *Buffers, prev []float64, rows int) {
+	s := l.Stride()
+	for r := 0; r < rows; r++ {
+		delta := b.Delta[r*l.Outputs : (r+1)*l.Outputs]
+		p := prev[r*l.Inputs : (r+1)*l.Inputs]
+		for k := range p {
+			var sum float64
+			for j, d := range delta {
+				sum += l.W[j*s+k] * d
+			}
+			p[k] = sum
 		}
 	}
 }
 
-// ApplyBias creates and returns a bias synapse for each neuron in l
-func (l *Layer) ApplyBias(weight synapse.WeightInitializer) []*synapse.Synapse {
-	biases := make([]*synapse.Synapse, len(l.Neurons))
-	for i := range l.Neurons {
-		biases[i] = synapse.NewSynapse(weight())
-		biases[i].IsBias = true
-		l.Neurons[i].In = append(l.Neurons[i].In, biases[i])
+// Gradient accumulates the parameter gradients of rows into grad, which is
+// shaped like Params()
+func (l *Layer) Gradient(in []float64, b *Buffers, rows int, grad []float64) {
+	s := l.Stride()
+	for r := 0; r < rows; r++ {
+		x := in[r*l.Inputs : (r+1)*l.Inputs]
+		delta := b.Delta[r*l.Outputs : (r+1)*l.Outputs]
+		for j, d := range delta {
+			g := grad[j*s : (j+1)*s]
+			for k, v := range x {
+				g[k] += d * v
+			}
+			if l.Bias {
+				g[l.Inputs] += d
+			}
+		}
+	}
+	if l.Norm != entities.NormNone {
+		gamma, beta := grad[len(l.W):len(l.W)+l.Outputs], grad[len(l.W)+l.Outputs:]
+		for j := range gamma {
+			gamma[j] += b.DGamma[j]
+			beta[j] += b.DBeta[j]
+		}
 	}
-	return biases
 }
 
 func (l Layer) String() string {
-	weights := make([][]float64, len(l.Neurons))
-	for i, n := range l.Neurons {
-		weights[i] = make([]float64, len(n.In))
-		for j, s := range n.In {
-			weights[i][j] = s.Weight
-		}
+	weights := make([][]float64, l.Outputs)
+	for j := range weights {
+		weights[j] = l.Row(j)
 	}
 	return fmt.Sprintf("%+v", weights)
 }
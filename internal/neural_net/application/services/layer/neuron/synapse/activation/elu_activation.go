@@ -0,0 +1,31 @@
+package activation
+
+import (
+	"main/internal/neural_net/domain/ports"
+	"math"
+)
+
+// ELU is an exponential linear unit activator
+type ELU struct {
+	Alpha float64
+}
+
+func NewELUActivation(alpha float64) ports.Differentiable {
+	return &ELU{Alpha: alpha}
+}
+
+// F is ELU(x)
+func (a *ELU) F(x float64) float64 {
+	if x > 0 {
+		return x
+	}
+	return a.Alpha * (math.Exp(x) - 1)
+}
+
+// Df is ELU'(x), expressed in y = ELU(x) for negative x
+func (a *ELU) Df(x, y float64) float64 {
+	if x > 0 {
+		return 1
+	}
+	return y + a.Alpha
+}
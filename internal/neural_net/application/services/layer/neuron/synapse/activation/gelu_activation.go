@@ -0,0 +1,25 @@
+package activation
+
+import (
+	"main/internal/neural_net/domain/ports"
+	"math"
+)
+
+// GELU is a gaussian error linear unit activator
+type GELU struct{}
+
+func NewGELUActivation() ports.Differentiable {
+	return &GELU{}
+}
+
+// F is GELU(x) = xΦ(x)
+func (a *GELU) F(x float64) float64 { return x * gaussianCDF(x) }
+
+// Df is GELU'(x) = Φ(x) + xφ(x)
+func (a *GELU) Df(x, y float64) float64 {
+	return gaussianCDF(x) + x*math.Exp(-x*x/2)/math.Sqrt(2*math.Pi)
+}
+
+func gaussianCDF(x float64) float64 {
+	return 0.5 * (1 + math.Erf(x/math.Sqrt2))
+}
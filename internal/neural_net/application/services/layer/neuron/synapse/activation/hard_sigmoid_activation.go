@@ -0,0 +1,26 @@
+package activation
+
+import (
+	"main/internal/neural_net/domain/ports"
+	"math"
+)
+
+// HardSigmoid is a piecewise linear approximation of Sigmoid
+type HardSigmoid struct{}
+
+func NewHardSigmoidActivation() ports.Differentiable {
+	return &HardSigmoid{}
+}
+
+// F is HardSigmoid(x) = clip(x/6 + 1/2, 0, 1)
+func (a *HardSigmoid) F(x float64) float64 {
+	return math.Min(math.Max(x/6+0.5, 0), 1)
+}
+
+// Df is HardSigmoid'(x)
+func (a *HardSigmoid) Df(x, y float64) float64 {
+	if x > -3 && x < 3 {
+		return 1.0 / 6
+	}
+	return 0
+}
@@ -0,0 +1,31 @@
+package activation
+
+import (
+	"main/internal/neural_net/domain/ports"
+)
+
+// LeakyReLU is a rectified linear unit activator with a small slope for
+// negative inputs
+type LeakyReLU struct {
+	Slope float64
+}
+
+func NewLeakyReLUActivation(slope float64) ports.Differentiable {
+	return &LeakyReLU{Slope: slope}
+}
+
+// F is LeakyReLU(x)
+func (a *LeakyReLU) F(x float64) float64 {
+	if x > 0 {
+		return x
+	}
+	return a.Slope * x
+}
+
+// Df is LeakyReLU'(x)
+func (a *LeakyReLU) Df(x, y float64) float64 {
+	if x > 0 {
+		return 1
+	}
+	return a.Slope
+}
@@ -15,4 +15,4 @@ func NewLinearActivation() ports.Differentiable {
 func (a *Linear) F(x float64) float64 { return x }
 
 // Df is constant
-func (a *Linear) Df(x float64) float64 { return 1 }
+func (a *Linear) Df(x, y float64) float64 { return 1 }
@@ -15,8 +15,8 @@ func NewReLUActivation() ports.Differentiable {
 // F is ReLU(x)
 func (a *ReLU) F(x float64) float64 { return math.Max(x, 0) }
 
-// Df is ReLU'(y), where y = ReLU(x)
-func (a *ReLU) Df(y float64) float64 {
+// Df is ReLU'(x)
+func (a *ReLU) Df(x, y float64) float64 {
 	if y > 0 {
 		return 1
 	}
@@ -0,0 +1,34 @@
+package activation
+
+import (
+	"main/internal/neural_net/domain/ports"
+	"math"
+)
+
+const (
+	seluLambda = 1.0507009873554804934193349852946
+	seluAlpha  = 1.6732632423543772848170429916717
+)
+
+// SELU is a scaled exponential linear unit activator
+type SELU struct{}
+
+func NewSELUActivation() ports.Differentiable {
+	return &SELU{}
+}
+
+// F is SELU(x)
+func (a *SELU) F(x float64) float64 {
+	if x > 0 {
+		return seluLambda * x
+	}
+	return seluLambda * seluAlpha * (math.Exp(x) - 1)
+}
+
+// Df is SELU'(x), expressed in y = SELU(x) for negative x
+func (a *SELU) Df(x, y float64) float64 {
+	if x > 0 {
+		return seluLambda
+	}
+	return y + seluLambda*seluAlpha
+}
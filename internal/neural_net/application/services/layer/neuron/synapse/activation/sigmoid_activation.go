@@ -15,8 +15,8 @@ func NewSigmoidActivation() ports.Differentiable {
 // F is Sigmoid(x)
 func (a *Sigmoid) F(x float64) float64 { return Logistic(x, 1) }
 
-// Df is Sigmoid'(y), where y = Sigmoid(x)
-func (a *Sigmoid) Df(y float64) float64 { return y * (1 - y) }
+// Df is Sigmoid'(x), expressed in y = Sigmoid(x)
+func (a *Sigmoid) Df(x, y float64) float64 { return y * (1 - y) }
 
 // Logistic is the logistic function
 func Logistic(x, a float64) float64 {
@@ -0,0 +1,21 @@
+package activation
+
+import (
+	"main/internal/neural_net/domain/ports"
+	"math"
+)
+
+// Softplus is a smooth approximation of ReLU
+type Softplus struct{}
+
+func NewSoftplusActivation() ports.Differentiable {
+	return &Softplus{}
+}
+
+// F is Softplus(x) = ln(1 + e^x), computed without overflow
+func (a *Softplus) F(x float64) float64 {
+	return math.Max(x, 0) + math.Log1p(math.Exp(-math.Abs(x)))
+}
+
+// Df is Softplus'(x) = Sigmoid(x)
+func (a *Softplus) Df(x, y float64) float64 { return Logistic(x, 1) }
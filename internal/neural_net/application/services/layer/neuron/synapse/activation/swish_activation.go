@@ -0,0 +1,21 @@
+package activation
+
+import (
+	"main/internal/neural_net/domain/ports"
+)
+
+// Swish is a self-gated activator, x * Sigmoid(x)
+type Swish struct{}
+
+func NewSwishActivation() ports.Differentiable {
+	return &Swish{}
+}
+
+// F is Swish(x)
+func (a *Swish) F(x float64) float64 { return x * Logistic(x, 1) }
+
+// Df is Swish'(x), expressed in y = Swish(x)
+func (a *Swish) Df(x, y float64) float64 {
+	s := Logistic(x, 1)
+	return y + s*(1-y)
+}
@@ -15,5 +15,5 @@ func NewTanhActivation() ports.Differentiable {
 // F is Tanh(x)
 func (a *Tanh) F(x float64) float64 { return (1 - math.Exp(-2*x)) / (1 + math.Exp(-2*x)) }
 
-// Df is Tanh'(y), where y = Tanh(x)
-func (a *Tanh) Df(y float64) float64 { return 1 - math.Pow(y, 2) }
+// Df is Tanh'(x), expressed in y = Tanh(x)
+func (a *Tanh) Df(x, y float64) float64 { return 1 - math.Pow(y, 2) }
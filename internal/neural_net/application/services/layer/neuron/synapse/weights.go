@@ -2,25 +2,25 @@ package synapse
 
 import "math/rand"
 
-// A WeightInitializer returns a (random) weight
-type WeightInitializer func() float64
+// A WeightInitializer returns a (random) weight drawn from rng
+type WeightInitializer func(rng *rand.Rand) float64
 
 // NewUniform returns a uniform weight generator
 func NewUniform(stdDev, mean float64) WeightInitializer {
-	return func() float64 { return Uniform(stdDev, mean) }
+	return func(rng *rand.Rand) float64 { return Uniform(rng, stdDev, mean) }
 }
 
 // Uniform samples a value from u(mean-stdDev/2,mean+stdDev/2)
-func Uniform(stdDev, mean float64) float64 {
-	return (rand.Float64()-0.5)*stdDev + mean
+func Uniform(rng *rand.Rand, stdDev, mean float64) float64 {
+	return (rng.Float64()-0.5)*stdDev + mean
 }
 
 // NewNormal returns a normal weight generator
 func NewNormal(stdDev, mean float64) WeightInitializer {
-	return func() float64 { return Normal(stdDev, mean) }
+	return func(rng *rand.Rand) float64 { return Normal(rng, stdDev, mean) }
 }
 
 // Normal samples a value from N(μ, σ)
-func Normal(stdDev, mean float64) float64 {
-	return rand.NormFloat64()*stdDev + mean
+func Normal(rng *rand.Rand, stdDev, mean float64) float64 {
+	return rng.NormFloat64()*stdDev + mean
 }
@@ -0,0 +1,179 @@
+package layer
+
+import (
+	"main/internal/neural_net/domain/entities"
+	"math"
+)
+
+const (
+	// normEpsilon keeps normalization away from a division by zero
+	normEpsilon = 1e-5
+	// normMomentum is the weight of each batch in the running statistics
+	normMomentum = 0.1
+)
+
+// NormState holds the normalization parameters of a layer
+type NormState struct {
+	Gamma, Beta []float64
+	// Running statistics, batch normalization only
+	Mean, Var []float64 `json:",omitempty"`
+}
+
+// NormState returns a copy of the normalization parameters of l, or nil if
+// l is not normalized
+func (l *Layer) NormState() *NormState {
+	if l.Norm == entities.NormNone {
+		return nil
+	}
+	return &NormState{
+		Gamma: append([]float64(nil), l.Gamma...),
+		Beta:  append([]float64(nil), l.Beta...),
+		Mean:  append([]float64(nil), l.Mean...),
+		Var:   append([]float64(nil), l.Var...),
+	}
+}
+
+// SetNormState sets the normalization parameters of l from s
+func (l *Layer) SetNormState(s *NormState) {
+	copy(l.Gamma, s.Gamma)
+	copy(l.Beta, s.Beta)
+	copy(l.Mean, s.Mean)
+	copy(l.Var, s.Var)
+}
+
+// normalize replaces the sums of rows by their normalized, scaled and
+// shifted values, keeping the normalized sums in b.Norm
+func (l *Layer) normalize(b *Buffers, rows int, train bool) {
+	o := l.Outputs
+	switch {
+	case l.Norm == entities.NormLayer:
+		for r := 0; r < rows; r++ {
+			sum := b.Sum[r*o : (r+1)*o]
+			var mean, variance float64
+			for _, v := range sum {
+				mean += v
+			}
+			mean /= float64(o)
+			for _, v := range sum {
+				variance += (v - mean) * (v - mean)
+			}
+			variance /= float64(o)
+			b.InvStd[r] = 1 / math.Sqrt(variance+normEpsilon)
+			for j, v := range sum {
+				b.Norm[r*o+j] = (v - mean) * b.InvStd[r]
+			}
+		}
+	case train:
+		for j := 0; j < o; j++ {
+			var mean, variance float64
+			for r := 0; r < rows; r++ {
+				mean += b.Sum[r*o+j]
+			}
+			mean /= float64(rows)
+			for r := 0; r < rows; r++ {
+				d := b.Sum[r*o+j] - mean
+				variance += d * d
+			}
+			variance /= float64(rows)
+			b.BatchMean[j], b.BatchVar[j] = mean, variance
+			b.InvStd[j] = 1 / math.Sqrt(variance+normEpsilon)
+			for r := 0; r < rows; r++ {
+				b.Norm[r*o+j] = (b.Sum[r*o+j] - mean) * b.InvStd[j]
+			}
+		}
+	default:
+		for j := 0; j < o; j++ {
+			inv := 1 / math.Sqrt(l.Var[j]+normEpsilon)
+			for r := 0; r < rows; r++ {
+				b.Norm[r*o+j] = (b.Sum[r*o+j] - l.Mean[j]) * inv
+			}
+		}
+	}
+	for i := 0; i < rows*o; i++ {
+		j := i % o
+		b.Sum[i] = l.Gamma[j]*b.Norm[i] + l.Beta[j]
+	}
+}
+
+// Denormalize turns the deltas of rows, taken with respect to the scaled
+// sums, into deltas with respect to the sums before normalization, keeping
+// the gradients of Gamma and Beta in b. It must follow a training pass and
+// does nothing for layers that are not normalized
+func (l *Layer) Denormalize(b *Buffers, rows int) {
+	if l.Norm == entities.NormNone {
+		return
+	}
+	o := l.Outputs
+	for j := range b.DGamma {
+		b.DGamma[j], b.DBeta[j] = 0, 0
+	}
+	for i := 0; i < rows*o; i++ {
+		j := i % o
+		b.DGamma[j] += b.Delta[i] * b.Norm[i]
+		b.DBeta[j] += b.Delta[i]
+		b.Delta[i] *= l.Gamma[j]
+	}
+
+	// With x̂ the normalized sums over a group of n values sharing the
+	// inverse standard deviation s: dx = s/n * (n dx̂ - Σdx̂ - x̂ Σdx̂x̂)
+	switch l.Norm {
+	case entities.NormLayer:
+		n := float64(o)
+		for r := 0; r < rows; r++ {
+			delta, norm := b.Delta[r*o:(r+1)*o], b.Norm[r*o:(r+1)*o]
+			var sum, dot float64
+			for j, d := range delta {
+				sum += d
+				dot += d * norm[j]
+			}
+			for j, d := range delta {
+				delta[j] = b.InvStd[r] / n * (n*d - sum - norm[j]*dot)
+			}
+		}
+	case entities.NormBatch:
+		n := float64(rows)
+		for j := 0; j < o; j++ {
+			var sum, dot float64
+			for r := 0; r < rows; r++ {
+				sum += b.Delta[r*o+j]
+				dot += b.Delta[r*o+j] * b.Norm[r*o+j]
+			}
+			for r := 0; r < rows; r++ {
+				i := r*o + j
+				b.Delta[i] = b.InvStd[j] / n * (n*b.Delta[i] - sum - b.Norm[i]*dot)
+			}
+		}
+	}
+}
+
+// Track folds the batch statistics of the last training pass through each
+// of bs, weighted by their number of rows, into the running statistics of a
+// batch normalized layer. Passes over shards of a batch thus count as one
+func (l *Layer) Track(bs ...*Buffers) {
+	if l.Norm != entities.NormBatch {
+		return
+	}
+	var total float64
+	for _, b := range bs {
+		total += float64(b.rows)
+	}
+	if total == 0 {
+		return
+	}
+	for j := 0; j < l.Outputs; j++ {
+		var mean, square float64
+		for _, b := range bs {
+			rows := float64(b.rows)
+			mean += rows * b.BatchMean[j]
+			square += rows * (b.BatchVar[j] + b.BatchMean[j]*b.BatchMean[j])
+		}
+		mean /= total
+		variance := math.Max(square/total-mean*mean, 0)
+		if total > 1 {
+			// Unbiased estimate
+			variance *= total / (total - 1)
+		}
+		l.Mean[j] += normMomentum * (mean - l.Mean[j])
+		l.Var[j] += normMomentum * (variance - l.Var[j])
+	}
+}
@@ -1,10 +1,113 @@
 package services
 
 import (
+	"fmt"
 	"main/internal/neural_net/domain/entities"
+	nnErrors "main/internal/neural_net/domain/errors"
+	"main/internal/neural_net/domain/utils"
 	"math"
+	"sync"
 )
 
+var losses = struct {
+	sync.RWMutex
+	named map[string]Loss
+}{named: make(map[string]Loss)}
+
+// RegisterLoss makes a custom loss available under name, for use in
+// Config.LossName. It panics if name is empty, already registered or loss
+// is nil
+func RegisterLoss(name string, loss Loss) {
+	losses.Lock()
+	defer losses.Unlock()
+	if name == "" || loss == nil {
+		panic("neural_net: RegisterLoss needs a name and a loss")
+	}
+	if _, dup := losses.named[name]; dup {
+		panic("neural_net: RegisterLoss called twice for loss " + name)
+	}
+	losses.named[name] = loss
+}
+
+// RegisteredLoss returns the custom loss registered under name
+func RegisteredLoss(name string) (Loss, error) {
+	losses.RLock()
+	loss, ok := losses.named[name]
+	losses.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", nnErrors.ErrUnknownLoss, name)
+	}
+	return loss, nil
+}
+
+// configLoss returns the loss configured in c, including its parameters
+func configLoss(c *entities.Config) Loss {
+	if c.LossName != "" {
+		if loss, err := RegisteredLoss(c.LossName); err == nil {
+			return loss
+		}
+	}
+	switch c.Loss {
+	case entities.LossCrossEntropy:
+		return CrossEntropy{Weights: c.ClassWeights}
+	case entities.LossBinaryCrossEntropy:
+		return BinaryCrossEntropy{Weights: c.ClassWeights}
+	case entities.LossFocal:
+		return Focal{Gamma: utils.Fparam(c.FocalGamma, 2), Weights: c.ClassWeights}
+	case entities.LossBinaryFocal:
+		return BinaryFocal{Gamma: utils.Fparam(c.FocalGamma, 2), Alpha: c.FocalAlpha, Weights: c.ClassWeights}
+	case entities.LossHuber:
+		return Huber{Delta: utils.Fparam(c.HuberDelta, 1)}
+	case entities.LossQuantile:
+		return Quantile{Quantiles: c.Quantiles}
+	}
+	return GetLoss(c.Loss)
+}
+
+// checkLossParams verifies that the loss of c suits its output activation and
+// that its per-output settings match its number of outputs
+func checkLossParams(c *entities.Config) error {
+	if c.LossName != "" || len(c.Layout) == 0 {
+		return nil
+	}
+	loss := c.Loss
+	if loss == entities.LossNone {
+		loss = modeLoss(c.Mode)
+	}
+	outputs := c.Layout[len(c.Layout)-1]
+	if loss == entities.LossCrossEntropy || loss == entities.LossFocal {
+		// Their derivatives assume softmax outputs
+		configs := layerConfigs(c)
+		if out := configs[len(configs)-1]; out.Activation != entities.ActivationSoftmax || out.ActivationName != "" {
+			return fmt.Errorf("%w: %s loss needs softmax outputs", nnErrors.ErrInvalidConfig, loss)
+		}
+	}
+	if c.ClassWeights != nil {
+		switch loss {
+		case entities.LossCrossEntropy, entities.LossFocal:
+			if len(c.ClassWeights) != outputs {
+				return fmt.Errorf("%w: %d class weights for %d outputs", nnErrors.ErrInvalidConfig, len(c.ClassWeights), outputs)
+			}
+		case entities.LossBinaryCrossEntropy, entities.LossBinaryFocal:
+			if len(c.ClassWeights) != 2 {
+				return fmt.Errorf("%w: %d class weights for a binary loss, want 2", nnErrors.ErrInvalidConfig, len(c.ClassWeights))
+			}
+		}
+	}
+	if loss == entities.LossQuantile && len(c.Quantiles) > 1 && len(c.Quantiles) != outputs {
+		return fmt.Errorf("%w: %d quantiles for %d outputs", nnErrors.ErrInvalidConfig, len(c.Quantiles), outputs)
+	}
+	return nil
+}
+
+// lossName describes the loss configured in c
+func lossName(c *entities.Config) string {
+	if c.LossName != "" {
+		return c.LossName
+	}
+	return c.Loss.String()
+}
+
 // GetLoss returns a loss function given a LossType
 func GetLoss(loss entities.LossType) Loss {
 	switch loss {
@@ -14,18 +117,36 @@ func GetLoss(loss entities.LossType) Loss {
 		return MeanSquared{}
 	case entities.LossBinaryCrossEntropy:
 		return BinaryCrossEntropy{}
+	case entities.LossHuber:
+		return Huber{Delta: 1}
+	case entities.LossMeanAbsolute:
+		return MeanAbsolute{}
+	case entities.LossLogCosh:
+		return LogCosh{}
+	case entities.LossQuantile:
+		return Quantile{}
+	case entities.LossFocal:
+		return Focal{Gamma: 2}
+	case entities.LossBinaryFocal:
+		return BinaryFocal{Gamma: 2}
 	}
 	return CrossEntropy{}
 }
 
 // Loss is satisfied by loss functions
 type Loss interface {
+	// F is the mean loss over a set of estimates
 	F(estimate, ideal [][]float64) float64
-	Df(estimate, ideal, activation float64) float64
+	// Df writes to delta the derivative of the loss of a single example with
+	// respect to the (pre-activation) sums of the output layer, given the
+	// derivative of the output activation for each output
+	Df(estimate, ideal, activation, delta []float64)
 }
 
-// CrossEntropy is CE loss
-type CrossEntropy struct{}
+// CrossEntropy is CE loss over softmax outputs, optionally weighted per class
+type CrossEntropy struct {
+	Weights []float64
+}
 
 // F is CE(...)
 func (l CrossEntropy) F(estimate, ideal [][]float64) float64 {
@@ -34,7 +155,7 @@ func (l CrossEntropy) F(estimate, ideal [][]float64) float64 {
 	for i := range estimate {
 		ce := 0.0
 		for j := range estimate[i] {
-			ce += ideal[i][j] * math.Log(estimate[i][j])
+			ce += classWeight(l.Weights, j) * ideal[i][j] * math.Log(estimate[i][j])
 		}
 
 		sum -= ce
@@ -43,21 +164,37 @@ func (l CrossEntropy) F(estimate, ideal [][]float64) float64 {
 }
 
 // Df is CE'(...)
-func (l CrossEntropy) Df(estimate, ideal, activation float64) float64 {
-	return estimate - ideal
+func (l CrossEntropy) Df(estimate, ideal, activation, delta []float64) {
+	if l.Weights == nil {
+		for j := range delta {
+			delta[j] = estimate[j] - ideal[j]
+		}
+		return
+	}
+	var total float64
+	for j := range ideal {
+		total += l.Weights[j] * ideal[j]
+	}
+	for j := range delta {
+		delta[j] = estimate[j]*total - l.Weights[j]*ideal[j]
+	}
 }
 
-// BinaryCrossEntropy is binary CE loss
-type BinaryCrossEntropy struct{}
+// BinaryCrossEntropy is binary CE loss over sigmoid outputs, optionally
+// weighted by Weights = [weight of negatives, weight of positives]
+type BinaryCrossEntropy struct {
+	Weights []float64
+}
 
 // F is CE(...)
 func (l BinaryCrossEntropy) F(estimate, ideal [][]float64) float64 {
 	epsilon := 1e-16
+	w0, w1 := classWeight(l.Weights, 0), classWeight(l.Weights, 1)
 	var sum float64
 	for i := range estimate {
 		ce := 0.0
 		for j := range estimate[i] {
-			ce += ideal[i][j]*math.Log(estimate[i][j]+epsilon) + (1.0-ideal[i][j])*math.Log(1.0-estimate[i][j]+epsilon)
+			ce += w1*ideal[i][j]*math.Log(estimate[i][j]+epsilon) + w0*(1.0-ideal[i][j])*math.Log(1.0-estimate[i][j]+epsilon)
 		}
 		sum -= ce
 	}
@@ -65,8 +202,111 @@ func (l BinaryCrossEntropy) F(estimate, ideal [][]float64) float64 {
 }
 
 // Df is CE'(...)
-func (l BinaryCrossEntropy) Df(estimate, ideal, activation float64) float64 {
-	return estimate - ideal
+func (l BinaryCrossEntropy) Df(estimate, ideal, activation, delta []float64) {
+	if l.Weights == nil {
+		for j := range delta {
+			delta[j] = estimate[j] - ideal[j]
+		}
+		return
+	}
+	w0, w1 := classWeight(l.Weights, 0), classWeight(l.Weights, 1)
+	for j := range delta {
+		delta[j] = estimate[j]*(w1*ideal[j]+w0*(1-ideal[j])) - w1*ideal[j]
+	}
+}
+
+// Focal is focal loss over softmax outputs, CE scaled by (1-p)^Gamma to
+// focus training on poorly classified examples, optionally weighted per class
+type Focal struct {
+	Gamma   float64
+	Weights []float64
+}
+
+// F is Focal(...)
+func (l Focal) F(estimate, ideal [][]float64) float64 {
+	epsilon := 1e-16
+	var sum float64
+	for i := range estimate {
+		for j, p := range estimate[i] {
+			sum -= classWeight(l.Weights, j) * ideal[i][j] * math.Pow(1-p, l.Gamma) * math.Log(p+epsilon)
+		}
+	}
+	return sum / float64(len(estimate))
+}
+
+// Df is Focal'(...)
+func (l Focal) Df(estimate, ideal, activation, delta []float64) {
+	// With h_j = p_j dL/dp_j, the softmax jacobian gives
+	// dL/dz_k = h_k - p_k Σ_j h_j
+	var total float64
+	for j := range delta {
+		p := clamp(estimate[j])
+		q := 1 - p
+		delta[j] = -classWeight(l.Weights, j) * ideal[j] *
+			(math.Pow(q, l.Gamma) - l.Gamma*p*math.Pow(q, l.Gamma-1)*math.Log(p))
+		total += delta[j]
+	}
+	for j := range delta {
+		delta[j] -= estimate[j] * total
+	}
+}
+
+// BinaryFocal is focal loss over sigmoid outputs. Positives are weighted by
+// Alpha and negatives by 1-Alpha, unless Alpha is zero; Weights =
+// [weight of negatives, weight of positives] applies on top
+type BinaryFocal struct {
+	Gamma   float64
+	Alpha   float64
+	Weights []float64
+}
+
+func (l BinaryFocal) weights() (w0, w1 float64) {
+	w0, w1 = classWeight(l.Weights, 0), classWeight(l.Weights, 1)
+	if l.Alpha != 0 {
+		w0, w1 = w0*(1-l.Alpha), w1*l.Alpha
+	}
+	return
+}
+
+// F is Focal(...)
+func (l BinaryFocal) F(estimate, ideal [][]float64) float64 {
+	epsilon := 1e-16
+	w0, w1 := l.weights()
+	var sum float64
+	for i := range estimate {
+		for j, p := range estimate[i] {
+			y := ideal[i][j]
+			sum -= w1*y*math.Pow(1-p, l.Gamma)*math.Log(p+epsilon) +
+				w0*(1-y)*math.Pow(p, l.Gamma)*math.Log(1-p+epsilon)
+		}
+	}
+	return sum / float64(len(estimate))
+}
+
+// Df is Focal'(...)
+func (l BinaryFocal) Df(estimate, ideal, activation, delta []float64) {
+	w0, w1 := l.weights()
+	for j := range delta {
+		p, y := clamp(estimate[j]), ideal[j]
+		q := 1 - p
+		delta[j] = w1*y*(l.Gamma*p*math.Pow(q, l.Gamma)*math.Log(p)-math.Pow(q, l.Gamma+1)) +
+			w0*(1-y)*(math.Pow(p, l.Gamma+1)-l.Gamma*math.Pow(p, l.Gamma)*q*math.Log(q))
+	}
+}
+
+// classWeight is the weight of class j, one when unweighted
+func classWeight(weights []float64, j int) float64 {
+	if weights == nil {
+		return 1
+	}
+	return weights[j]
+}
+
+// clamp keeps a probability away from 0 and 1, where focal loss derivatives
+// are singular
+func clamp(p float64) float64 {
+	const epsilon = 1e-15
+	return math.Max(epsilon, math.Min(1-epsilon, p))
 }
 
 // MeanSquared in MSE loss
@@ -83,7 +323,126 @@ func (l MeanSquared) F(estimate, ideal [][]float64) float64 {
 	return sum / float64(len(estimate)*len(estimate[0]))
 }
 
-// Df is MSE'(...)
-func (l MeanSquared) Df(estimate, ideal, activation float64) float64 {
-	return activation * (estimate - ideal)
+// Df is MSE'(...) without its factor 2/outputs, the scaling networks
+// trained with MSE have always used
+func (l MeanSquared) Df(estimate, ideal, activation, delta []float64) {
+	for j := range delta {
+		delta[j] = activation[j] * (estimate[j] - ideal[j])
+	}
+}
+
+// Huber is quadratic for errors up to Delta and linear beyond, which makes
+// it less sensitive to outliers than MSE
+type Huber struct {
+	Delta float64
+}
+
+// F is Huber(...)
+func (l Huber) F(estimate, ideal [][]float64) float64 {
+	var sum float64
+	for i := range estimate {
+		for j := range estimate[i] {
+			e := math.Abs(estimate[i][j] - ideal[i][j])
+			if e <= l.Delta {
+				sum += 0.5 * e * e
+			} else {
+				sum += l.Delta * (e - 0.5*l.Delta)
+			}
+		}
+	}
+	return sum / float64(len(estimate)*len(estimate[0]))
+}
+
+// Df is Huber'(...)
+func (l Huber) Df(estimate, ideal, activation, delta []float64) {
+	for j := range delta {
+		e := math.Max(-l.Delta, math.Min(l.Delta, estimate[j]-ideal[j]))
+		delta[j] = activation[j] * e / float64(len(delta))
+	}
+}
+
+// MeanAbsolute is MAE loss
+type MeanAbsolute struct{}
+
+// F is MAE(...)
+func (l MeanAbsolute) F(estimate, ideal [][]float64) float64 {
+	var sum float64
+	for i := range estimate {
+		for j := range estimate[i] {
+			sum += math.Abs(estimate[i][j] - ideal[i][j])
+		}
+	}
+	return sum / float64(len(estimate)*len(estimate[0]))
+}
+
+// Df is MAE'(...)
+func (l MeanAbsolute) Df(estimate, ideal, activation, delta []float64) {
+	for j := range delta {
+		delta[j] = activation[j] * utils.Sgn(estimate[j]-ideal[j]) / float64(len(delta))
+	}
+}
+
+// LogCosh is log(cosh(error)) loss, quadratic for small and linear for
+// large errors
+type LogCosh struct{}
+
+// F is LogCosh(...)
+func (l LogCosh) F(estimate, ideal [][]float64) float64 {
+	var sum float64
+	for i := range estimate {
+		for j := range estimate[i] {
+			// log(cosh(e)) = |e| + log(1 + exp(-2|e|)) - log(2), without overflow
+			e := math.Abs(estimate[i][j] - ideal[i][j])
+			sum += e + math.Log1p(math.Exp(-2*e)) - math.Ln2
+		}
+	}
+	return sum / float64(len(estimate)*len(estimate[0]))
+}
+
+// Df is LogCosh'(...)
+func (l LogCosh) Df(estimate, ideal, activation, delta []float64) {
+	for j := range delta {
+		delta[j] = activation[j] * math.Tanh(estimate[j]-ideal[j]) / float64(len(delta))
+	}
+}
+
+// Quantile is the pinball loss. Output j estimates quantile Quantiles[j],
+// so that one network can predict several quantiles at once; a single
+// quantile applies to all outputs and none defaults to the median
+type Quantile struct {
+	Quantiles []float64
+}
+
+func (l Quantile) quantile(j int) float64 {
+	switch {
+	case len(l.Quantiles) == 0:
+		return 0.5
+	case len(l.Quantiles) == 1:
+		return l.Quantiles[0]
+	}
+	return l.Quantiles[j]
+}
+
+// F is Pinball(...)
+func (l Quantile) F(estimate, ideal [][]float64) float64 {
+	var sum float64
+	for i := range estimate {
+		for j := range estimate[i] {
+			q, e := l.quantile(j), ideal[i][j]-estimate[i][j]
+			sum += math.Max(q*e, (q-1)*e)
+		}
+	}
+	return sum / float64(len(estimate)*len(estimate[0]))
+}
+
+// Df is Pinball'(...)
+func (l Quantile) Df(estimate, ideal, activation, delta []float64) {
+	for j := range delta {
+		q := l.quantile(j)
+		if estimate[j] < ideal[j] {
+			delta[j] = -activation[j] * q / float64(len(delta))
+		} else {
+			delta[j] = activation[j] * (1 - q) / float64(len(delta))
+		}
+	}
 }
@@ -0,0 +1,333 @@
+package metrics
+
+import (
+	"main/internal/neural_net/domain/entities"
+	"main/internal/neural_net/domain/utils"
+	"math"
+	"sort"
+)
+
+// Average selects how the counts of every class make a single score
+type Average int
+
+const (
+	// Macro is the mean of the scores of the classes present in the
+	// responses or the decisions
+	Macro Average = 0
+	// Micro is the score of the counts of all classes pooled
+	Micro Average = 1
+)
+
+// Decision turns the outputs of a network into classes
+type Decision struct {
+	Mode entities.Mode
+	// Thresholds the outputs must reach to decide their class, one per
+	// output (default 0.5). Ignored for ModeMultiClass
+	Thresholds []float64
+}
+
+// Decide returns the classes decided for the outputs of an example: the
+// largest for ModeMultiClass, and every output reaching its threshold
+// otherwise
+func (d Decision) Decide(output []float64) []bool {
+	classes := make([]bool, len(output))
+	if d.Mode == entities.ModeMultiClass {
+		classes[utils.ArgMax(output)] = true
+		return classes
+	}
+	for j, o := range output {
+		classes[j] = o >= d.threshold(j)
+	}
+	return classes
+}
+
+func (d Decision) threshold(j int) float64 {
+	if j < len(d.Thresholds) {
+		return d.Thresholds[j]
+	}
+	return 0.5
+}
+
+// actual returns the classes of a response, which reach 0.5
+func (d Decision) actual(response []float64) []bool {
+	return Decision{Mode: d.Mode}.Decide(response)
+}
+
+// Counts are the decisions of a class over a set of examples
+type Counts struct {
+	TruePositives, FalsePositives, FalseNegatives, TrueNegatives int
+}
+
+// ClassCounts returns the counts of every output as a class
+func ClassCounts(d Decision, estimate, ideal [][]float64) []Counts {
+	if len(estimate) == 0 {
+		return nil
+	}
+	counts := make([]Counts, len(ideal[0]))
+	for i := range estimate {
+		decided, actual := d.Decide(estimate[i]), d.actual(ideal[i])
+		for j := range counts {
+			c := &counts[j]
+			switch {
+			case decided[j] && actual[j]:
+				c.TruePositives++
+			case decided[j]:
+				c.FalsePositives++
+			case actual[j]:
+				c.FalseNegatives++
+			default:
+				c.TrueNegatives++
+			}
+		}
+	}
+	return counts
+}
+
+// ConfusionMatrix counts the examples of every true class (rows) by decided
+// class (columns). A single output has classes 0 and 1, several outputs one
+// class each, decided by the largest: multi-label outputs are better
+// described by ClassCounts
+func ConfusionMatrix(d Decision, estimate, ideal [][]float64) [][]int {
+	if len(estimate) == 0 {
+		return nil
+	}
+	size := len(ideal[0])
+	if size == 1 {
+		size = 2
+	}
+	m := make([][]int, size)
+	for i := range m {
+		m[i] = make([]int, size)
+	}
+	for i := range estimate {
+		m[Decision{}.class(ideal[i])][d.class(estimate[i])]++
+	}
+	return m
+}
+
+func (d Decision) class(output []float64) int {
+	if len(output) > 1 {
+		return utils.ArgMax(output)
+	}
+	if d.Decide(output)[0] {
+		return 1
+	}
+	return 0
+}
+
+// Accuracy is the fraction of examples whose decided classes all match the
+// responses
+func Accuracy(d Decision, estimate, ideal [][]float64) float64 {
+	correct := 0
+	for i := range estimate {
+		decided, actual := d.Decide(estimate[i]), d.actual(ideal[i])
+		match := true
+		for j := range decided {
+			match = match && decided[j] == actual[j]
+		}
+		if match {
+			correct++
+		}
+	}
+	return ratio(float64(correct), float64(len(estimate)))
+}
+
+// Precision is the fraction of decided classes that are right
+func Precision(d Decision, estimate, ideal [][]float64, avg Average) float64 {
+	return average(ClassCounts(d, estimate, ideal), avg, func(c Counts) (float64, float64) {
+		return float64(c.TruePositives), float64(c.TruePositives + c.FalsePositives)
+	})
+}
+
+// Recall is the fraction of true classes that are decided
+func Recall(d Decision, estimate, ideal [][]float64, avg Average) float64 {
+	return average(ClassCounts(d, estimate, ideal), avg, func(c Counts) (float64, float64) {
+		return float64(c.TruePositives), float64(c.TruePositives + c.FalseNegatives)
+	})
+}
+
+// F1 is the harmonic mean of precision and recall
+func F1(d Decision, estimate, ideal [][]float64, avg Average) float64 {
+	return average(ClassCounts(d, estimate, ideal), avg, func(c Counts) (float64, float64) {
+		return float64(2 * c.TruePositives), float64(2*c.TruePositives + c.FalsePositives + c.FalseNegatives)
+	})
+}
+
+// average combines the ratio score of counts. Classes absent from both the
+// responses and the decisions are left out, other undefined ratios are zero
+func average(counts []Counts, avg Average, score func(Counts) (float64, float64)) float64 {
+	if avg == Micro {
+		var pooled Counts
+		for _, c := range counts {
+			pooled.TruePositives += c.TruePositives
+			pooled.FalsePositives += c.FalsePositives
+			pooled.FalseNegatives += c.FalseNegatives
+		}
+		counts = []Counts{pooled}
+	}
+	scores := make([]float64, len(counts))
+	for j, c := range counts {
+		scores[j] = math.NaN()
+		if c.TruePositives+c.FalsePositives+c.FalseNegatives == 0 {
+			continue
+		}
+		num, den := score(c)
+		scores[j] = 0
+		if den > 0 {
+			scores[j] = num / den
+		}
+	}
+	return mean(scores)
+}
+
+// ROCAUC is the area under the ROC curve of every output against responses
+// reaching 0.5, averaged over the outputs with both classes present
+func ROCAUC(estimate, ideal [][]float64) float64 {
+	return perOutput(estimate, ideal, func(order []int, score func(int) float64, positive func(int) bool) float64 {
+		// Mann-Whitney U statistic, ties ranked by their mean rank
+		var positives, negatives, ranks float64
+		for lo := 0; lo < len(order); {
+			hi := lo
+			for hi < len(order) && score(order[hi]) == score(order[lo]) {
+				hi++
+			}
+			rank := float64(lo+hi+1) / 2
+			for _, i := range order[lo:hi] {
+				if positive(i) {
+					positives++
+					ranks += rank
+				} else {
+					negatives++
+				}
+			}
+			lo = hi
+		}
+		return ratio(ranks-positives*(positives+1)/2, positives*negatives)
+	})
+}
+
+// PRAUC is the area under the precision-recall curve of every output
+// against responses reaching 0.5, as the average precision, averaged over
+// the outputs with positive responses
+func PRAUC(estimate, ideal [][]float64) float64 {
+	return perOutput(estimate, ideal, func(order []int, score func(int) float64, positive func(int) bool) float64 {
+		var positives float64
+		for _, i := range order {
+			if positive(i) {
+				positives++
+			}
+		}
+		if positives == 0 {
+			return math.NaN()
+		}
+		// Thresholds from the highest score down, ties taken together
+		var tp, fp, ap float64
+		for hi := len(order); hi > 0; {
+			lo, step := hi, 0.0
+			for lo > 0 && score(order[lo-1]) == score(order[hi-1]) {
+				lo--
+				if positive(order[lo]) {
+					step++
+				} else {
+					fp++
+				}
+			}
+			tp += step
+			ap += step / positives * tp / (tp + fp)
+			hi = lo
+		}
+		return ap
+	})
+}
+
+// perOutput averages the area computed for every output, given the examples
+// in ascending order of the output
+func perOutput(estimate, ideal [][]float64,
+	area func(order []int, score func(int) float64, positive func(int) bool) float64) float64 {
+	if len(estimate) == 0 {
+		return math.NaN()
+	}
+	areas := make([]float64, len(ideal[0]))
+	order := make([]int, len(estimate))
+	for j := range areas {
+		score := func(i int) float64 { return estimate[i][j] }
+		ascending(order, score)
+		areas[j] = area(order, score, func(i int) bool { return ideal[i][j] >= 0.5 })
+	}
+	return mean(areas)
+}
+
+// ascending sets order to the indexes of the examples by ascending score
+func ascending(order []int, score func(int) float64) {
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return score(order[a]) < score(order[b]) })
+}
+
+// Thresholds returns the threshold of every output maximizing the F1 score
+// of its label on the examples, halfway between the lowest output decided
+// positive and the next lower one. Outputs without positive responses keep
+// 0.5
+func Thresholds(estimate, ideal [][]float64) []float64 {
+	if len(estimate) == 0 {
+		return nil
+	}
+	thresholds := make([]float64, len(ideal[0]))
+	order := make([]int, len(estimate))
+	for j := range thresholds {
+		thresholds[j] = 0.5
+		score := func(i int) float64 { return estimate[i][j] }
+		positive := func(i int) bool { return ideal[i][j] >= 0.5 }
+		var positives float64
+		for i := range ideal {
+			if positive(i) {
+				positives++
+			}
+		}
+		if positives == 0 {
+			continue
+		}
+		ascending(order, score)
+		// Lower the threshold through the outputs from the highest, ties
+		// taken together: F1 is 2TP/(2TP+FP+FN) with FN = positives-TP
+		var tp, fp, best float64
+		for hi := len(order); hi > 0; {
+			lo := hi
+			for lo > 0 && score(order[lo-1]) == score(order[hi-1]) {
+				lo--
+				if positive(order[lo]) {
+					tp++
+				} else {
+					fp++
+				}
+			}
+			if f1 := 2 * tp / (tp + fp + positives); f1 > best {
+				best, thresholds[j] = f1, score(order[lo])
+				if lo > 0 {
+					thresholds[j] = (thresholds[j] + score(order[lo-1])) / 2
+				}
+			}
+			hi = lo
+		}
+	}
+	return thresholds
+}
+
+// LogLoss is the cross-entropy of the outputs as probabilities, clipped away
+// from 0 and 1: categorical for ModeMultiClass, and summed over the outputs
+// as independent probabilities otherwise
+func LogLoss(mode entities.Mode, estimate, ideal [][]float64) float64 {
+	const eps = 1e-15
+	var sum float64
+	for i := range estimate {
+		for j, y := range ideal[i] {
+			p := math.Min(math.Max(estimate[i][j], eps), 1-eps)
+			sum -= y * math.Log(p)
+			if mode != entities.ModeMultiClass {
+				sum -= (1 - y) * math.Log(1-p)
+			}
+		}
+	}
+	return ratio(sum, float64(len(estimate)))
+}
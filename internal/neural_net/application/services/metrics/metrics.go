@@ -0,0 +1,68 @@
+// Package metrics measures the quality of predictions against responses.
+// Every function takes the outputs of a network and the expected responses,
+// one row per example
+package metrics
+
+import (
+	"main/internal/neural_net/domain/entities"
+	"math"
+)
+
+// Evaluate computes metric m of the predictions of a network, whose classes
+// are decided by d. The loss depends on the network and is not computed here: it is
+// NaN, like every metric that is undefined on the examples
+func Evaluate(m entities.MetricType, d Decision, estimate, ideal [][]float64) float64 {
+	switch m {
+	case entities.MetricAccuracy:
+		return Accuracy(d, estimate, ideal)
+	case entities.MetricPrecision:
+		return Precision(d, estimate, ideal, Macro)
+	case entities.MetricRecall:
+		return Recall(d, estimate, ideal, Macro)
+	case entities.MetricF1:
+		return F1(d, estimate, ideal, Macro)
+	case entities.MetricMicroPrecision:
+		return Precision(d, estimate, ideal, Micro)
+	case entities.MetricMicroRecall:
+		return Recall(d, estimate, ideal, Micro)
+	case entities.MetricMicroF1:
+		return F1(d, estimate, ideal, Micro)
+	case entities.MetricROCAUC:
+		return ROCAUC(estimate, ideal)
+	case entities.MetricPRAUC:
+		return PRAUC(estimate, ideal)
+	case entities.MetricLogLoss:
+		return LogLoss(d.Mode, estimate, ideal)
+	case entities.MetricMAE:
+		return MAE(estimate, ideal)
+	case entities.MetricRMSE:
+		return RMSE(estimate, ideal)
+	case entities.MetricR2:
+		return R2(estimate, ideal)
+	case entities.MetricMAPE:
+		return MAPE(estimate, ideal)
+	case entities.MetricDirectionalAccuracy:
+		return DirectionalAccuracy(estimate, ideal)
+	}
+	return math.NaN()
+}
+
+// ratio is num/den, NaN when den is zero
+func ratio(num, den float64) float64 {
+	if den == 0 {
+		return math.NaN()
+	}
+	return num / den
+}
+
+// mean is the mean of the values that are not NaN, NaN if all are
+func mean(values []float64) float64 {
+	var sum, count float64
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			sum += v
+			count++
+		}
+	}
+	return ratio(sum, count)
+}
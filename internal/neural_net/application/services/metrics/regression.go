@@ -0,0 +1,95 @@
+package metrics
+
+import "math"
+
+// MAE is the mean absolute error over all outputs
+func MAE(estimate, ideal [][]float64) float64 {
+	var sum, count float64
+	for i := range estimate {
+		for j, y := range ideal[i] {
+			sum += math.Abs(estimate[i][j] - y)
+			count++
+		}
+	}
+	return ratio(sum, count)
+}
+
+// RMSE is the root mean squared error over all outputs
+func RMSE(estimate, ideal [][]float64) float64 {
+	var sum, count float64
+	for i := range estimate {
+		for j, y := range ideal[i] {
+			d := estimate[i][j] - y
+			sum += d * d
+			count++
+		}
+	}
+	return math.Sqrt(ratio(sum, count))
+}
+
+// R2 is the coefficient of determination of every output, averaged. Outputs
+// whose responses are constant are left out
+func R2(estimate, ideal [][]float64) float64 {
+	if len(ideal) == 0 {
+		return math.NaN()
+	}
+	scores := make([]float64, len(ideal[0]))
+	for j := range scores {
+		var avg float64
+		for i := range ideal {
+			avg += ideal[i][j] / float64(len(ideal))
+		}
+		var residual, total float64
+		for i := range ideal {
+			d, t := ideal[i][j]-estimate[i][j], ideal[i][j]-avg
+			residual += d * d
+			total += t * t
+		}
+		scores[j] = 1 - ratio(residual, total)
+	}
+	return mean(scores)
+}
+
+// MAPE is the mean absolute percentage error, as a fraction, over the
+// nonzero responses
+func MAPE(estimate, ideal [][]float64) float64 {
+	var sum, count float64
+	for i := range estimate {
+		for j, y := range ideal[i] {
+			if y == 0 {
+				continue
+			}
+			sum += math.Abs((estimate[i][j] - y) / y)
+			count++
+		}
+	}
+	return ratio(sum, count)
+}
+
+// DirectionalAccuracy is the fraction of outputs of the examples after the
+// first, in time order, that move from the response of the previous example
+// in the same direction as their own response. Unchanged responses only
+// match unchanged outputs
+func DirectionalAccuracy(estimate, ideal [][]float64) float64 {
+	var hits, count float64
+	for i := 1; i < len(estimate); i++ {
+		for j, y := range ideal[i] {
+			prev := ideal[i-1][j]
+			if sign(estimate[i][j]-prev) == sign(y-prev) {
+				hits++
+			}
+			count++
+		}
+	}
+	return ratio(hits, count)
+}
+
+func sign(x float64) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
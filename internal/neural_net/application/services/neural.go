@@ -6,88 +6,280 @@ import (
 	"main/internal/neural_net/application/services/layer/neuron/synapse"
 	"main/internal/neural_net/domain/entities"
 	"main/internal/neural_net/domain/utils"
+	"math/rand"
 )
 
 // Neural is a neural network
 type Neural struct {
 	Layers []*layer.Layer
-	Biases [][]*synapse.Synapse
 	Config *entities.Config
+	// Thresholds deciding the labels of ModeBinary and ModeMultiLabel
+	// outputs, see FitThresholds (default 0.5)
+	Thresholds []float64
+	// Calibration of ModeBinary outputs applied by Predict, see Calibrate
+	Calibration *Calibration
+
+	ws *workspace
 }
 
-// NewNeural returns a new neural network
+// NewNeural returns a new neural network, its weights drawn from c.Seed. It
+// panics if c names an activation or loss that was never registered or its
+// loss settings do not fit its output layer, see CheckConfig
 func NewNeural(c *entities.Config) *Neural {
 
 	if c.Weight == nil {
 		c.Weight = synapse.NewUniform(0.5, 0)
 	}
-	if c.Activation == entities.ActivationNone {
+	for c.Seed == 0 {
+		c.Seed = rand.Int63()
+	}
+	if c.Activation == entities.ActivationNone && c.ActivationName == "" {
 		c.Activation = entities.ActivationSigmoid
 	}
-	if c.Loss == entities.LossNone {
-		switch c.Mode {
-		case entities.ModeMultiClass, entities.ModeMultiLabel:
-			c.Loss = entities.LossCrossEntropy
-		case entities.ModeBinary:
-			c.Loss = entities.LossBinaryCrossEntropy
-		default:
-			c.Loss = entities.LossMeanSquared
+	if c.Loss == entities.LossNone && c.LossName == "" {
+		c.Loss = modeLoss(c.Mode)
+	}
+
+	if err := CheckConfig(c); err != nil {
+		panic(err)
+	}
+
+	n := &Neural{
+		Layers: initializeLayers(c),
+		Config: c,
+	}
+	n.ws = newWorkspace(n, 1)
+	return n
+}
+
+// modeLoss is the loss of networks of mode that configure none
+func modeLoss(mode entities.Mode) entities.LossType {
+	switch mode {
+	case entities.ModeMultiClass:
+		return entities.LossCrossEntropy
+	case entities.ModeBinary, entities.ModeMultiLabel:
+		return entities.LossBinaryCrossEntropy
+	}
+	return entities.LossMeanSquared
+}
+
+// layerConfigs resolves the settings of every layer, falling back to the
+// network-wide ones for anything not set per layer
+func layerConfigs(c *entities.Config) []entities.LayerConfig {
+	configs := make([]entities.LayerConfig, len(c.Layout))
+	copy(configs, c.Layers)
+	for i := range configs {
+		lc := &configs[i]
+		output := i == len(configs)-1
+		if lc.Activation == entities.ActivationNone && lc.ActivationName == "" {
+			lc.Activation, lc.ActivationName = c.Activation, c.ActivationName
+			if output && c.Mode != entities.ModeDefault {
+				lc.Activation, lc.ActivationName = utils.OutputActivation(c.Mode), ""
+			}
+		}
+		if lc.ActivationAlpha == 0 {
+			lc.ActivationAlpha = c.ActivationAlpha
+		}
+		if lc.Bias == nil {
+			lc.Bias = entities.Bool(c.Bias && !(output && c.Mode == entities.ModeRegression))
+		}
+		if lc.Weight == nil {
+			lc.Weight = c.Weight
+		}
+		if output {
+			// Dropout only applies to hidden layers
+			lc.Dropout = 0
 		}
 	}
+	return configs
+}
 
-	layers := initializeLayers(c)
+func initializeLayers(c *entities.Config) []*layer.Layer {
+	c.Layers = layerConfigs(c)
 
-	var biases [][]*synapse.Synapse
-	if c.Bias {
-		biases = make([][]*synapse.Synapse, len(layers))
-		for i := 0; i < len(layers); i++ {
-			if c.Mode == entities.ModeRegression && i == len(layers)-1 {
-				continue
+	layers := make([]*layer.Layer, len(c.Layout))
+	for i, lc := range c.Layers {
+		inputs := c.Inputs
+		if i > 0 {
+			inputs = c.Layout[i-1]
+		}
+		layers[i] = layer.NewLayer(inputs, c.Layout[i], lc)
+	}
+
+	// Weights are drawn in the order the network used to be wired (hidden
+	// connections, then inputs, then biases) so that a seed keeps producing
+	// the same networks
+	rng := rand.New(rand.NewSource(c.Seed))
+	for i, l := range layers[1:] {
+		weight := c.Layers[i+1].Weight
+		for k := 0; k < l.Inputs; k++ {
+			for j := 0; j < l.Outputs; j++ {
+				l.Row(j)[k] = weight(rng)
 			}
-			biases[i] = layers[i].ApplyBias(c.Weight)
+		}
+	}
+	for j := 0; j < layers[0].Outputs; j++ {
+		row := layers[0].Row(j)
+		for k := 0; k < layers[0].Inputs; k++ {
+			row[k] = c.Layers[0].Weight(rng)
+		}
+	}
+	for i, l := range layers {
+		if !l.Bias {
+			continue
+		}
+		for j := 0; j < l.Outputs; j++ {
+			l.Row(j)[l.Inputs] = c.Layers[i].Weight(rng)
 		}
 	}
 
-	return &Neural{
-		Layers: layers,
-		Biases: biases,
-		Config: c,
+	return layers
+}
+
+// workspace holds the buffers of a pass through the network for up to
+// size rows. Dropout is only applied in training workspaces, drawing from
+// rng
+type workspace struct {
+	size   int
+	train  bool
+	rng    *rand.Rand
+	in     []float64
+	layers []*layer.Buffers
+	dact   []float64
+
+	// Rows of the outputs and responses, for computing the loss
+	estimate, ideal [][]float64
+}
+
+func newWorkspace(n *Neural, size int) *workspace {
+	ws := &workspace{
+		size:   size,
+		in:     make([]float64, size*n.Config.Inputs),
+		layers: make([]*layer.Buffers, len(n.Layers)),
+		dact:   make([]float64, n.Layers[len(n.Layers)-1].Outputs),
+	}
+	for i, l := range n.Layers {
+		ws.layers[i] = l.NewBuffers(size)
 	}
+	return ws
 }
 
-func initializeLayers(c *entities.Config) []*layer.Layer {
-	layers := make([]*layer.Layer, len(c.Layout))
-	for i := range layers {
-		act := c.Activation
-		if i == (len(layers)-1) && c.Mode != entities.ModeDefault {
-			act = utils.OutputActivation(c.Mode)
+// newTrainingWorkspace returns a workspace that applies dropout
+func newTrainingWorkspace(n *Neural, size int) *workspace {
+	ws := newWorkspace(n, size)
+	ws.train = true
+	ws.rng = rand.New(rand.NewSource(0))
+	ws.estimate, ws.ideal = make([][]float64, size), make([][]float64, size)
+	return ws
+}
+
+// dropped reports whether the outputs of layer i are masked by dropout
+func (ws *workspace) dropped(i int) bool {
+	return ws.train && ws.layers[i].Dropped != nil
+}
+
+// input returns the input rows of layer i
+func (ws *workspace) input(i int) []float64 {
+	switch {
+	case i == 0:
+		return ws.in
+	case ws.dropped(i - 1):
+		return ws.layers[i-1].Dropped
+	}
+	return ws.layers[i-1].Out
+}
+
+// output returns the output rows of the network
+func (ws *workspace) output() []float64 {
+	return ws.layers[len(ws.layers)-1].Out
+}
+
+// load copies the inputs of examples into the input rows
+func (ws *workspace) load(examples Examples) {
+	inputs := len(ws.in) / ws.size
+	for r, e := range examples {
+		copy(ws.in[r*inputs:(r+1)*inputs], e.Input)
+	}
+}
+
+// forward computes a forward pass over the first rows input rows of ws
+func (n *Neural) forward(ws *workspace, rows int) {
+	for i, l := range n.Layers {
+		l.Forward(ws.input(i), ws.layers[i], rows, ws.train)
+		if ws.dropped(i) {
+			l.Drop(ws.layers[i], rows, ws.rng.Float64)
 		}
-		layers[i] = layer.NewLayer(c.Layout[i], act)
 	}
+}
 
-	for i := 0; i < len(layers)-1; i++ {
-		layers[i].Connect(layers[i+1], c.Weight)
+// loss computes the loss of examples, which must be the examples last
+// passed forward through the training workspace ws
+func (n *Neural) loss(ws *workspace, examples Examples, loss Loss) float64 {
+	out := ws.output()
+	outputs := len(out) / ws.size
+	for r, e := range examples {
+		ws.estimate[r] = out[r*outputs : (r+1)*outputs]
+		ws.ideal[r] = e.Response
 	}
+	return loss.F(ws.estimate[:len(examples)], ws.ideal[:len(examples)])
+}
 
-	for _, neuron := range layers[0].Neurons {
-		neuron.In = make([]*synapse.Synapse, c.Inputs)
-		for i := range neuron.In {
-			neuron.In[i] = synapse.NewSynapse(c.Weight())
+// backward computes the deltas of every layer of loss for examples, which
+// must be the examples last passed forward through ws
+func (n *Neural) backward(ws *workspace, examples Examples, loss Loss) {
+	rows := len(examples)
+	last := len(n.Layers) - 1
+	out, b := n.Layers[last], ws.layers[last]
+	act := out.Activation()
+	for r, e := range examples {
+		lo, hi := r*out.Outputs, (r+1)*out.Outputs
+		for j := range ws.dact {
+			ws.dact[j] = act.Df(b.Sum[lo+j], b.Out[lo+j])
 		}
+		loss.Df(b.Out[lo:hi], e.Response, ws.dact, b.Delta[lo:hi])
 	}
+	out.Denormalize(b, rows)
 
-	return layers
+	for i := last; i > 0; i-- {
+		n.Layers[i].Backward(ws.layers[i], ws.layers[i-1].Delta, rows)
+		if ws.dropped(i - 1) {
+			n.Layers[i-1].Undrop(ws.layers[i-1], rows)
+		}
+		n.Layers[i-1].Derive(ws.layers[i-1], rows)
+		n.Layers[i-1].Denormalize(ws.layers[i-1], rows)
+	}
 }
 
-func (n *Neural) Fire() {
-	for _, b := range n.Biases {
-		for _, s := range b {
-			s.Fire(1)
+// track updates the running statistics of batch normalized layers from the
+// last training passes through wss, which together covered one batch
+func (n *Neural) track(wss ...*workspace) {
+	for i, l := range n.Layers {
+		if l.Norm != entities.NormBatch {
+			continue
+		}
+		bs := make([]*layer.Buffers, len(wss))
+		for w, ws := range wss {
+			bs[w] = ws.layers[i]
 		}
+		l.Track(bs...)
 	}
-	for _, l := range n.Layers {
-		l.Fire()
+}
+
+// gradients accumulates the parameter gradients of rows into grads, which
+// holds one slice per layer shaped like its parameters
+func (n *Neural) gradients(ws *workspace, rows int, grads [][]float64) {
+	for i, l := range n.Layers {
+		l.Gradient(ws.input(i), ws.layers[i], rows, grads[i])
+	}
+}
+
+// newGradients allocates zeroed gradient buffers shaped like the parameters
+func (n *Neural) newGradients() [][]float64 {
+	grads := make([][]float64, len(n.Layers))
+	for i, l := range n.Layers {
+		grads[i] = make([]float64, len(l.Params()))
 	}
+	return grads
 }
 
 // Forward computes a forward pass
@@ -95,33 +287,27 @@ func (n *Neural) Forward(input []float64) error {
 	if len(input) != n.Config.Inputs {
 		return fmt.Errorf("Invalid input dimension - expected: %d got: %d", n.Config.Inputs, len(input))
 	}
-	for _, n := range n.Layers[0].Neurons {
-		for i := 0; i < len(input); i++ {
-			n.In[i].Fire(input[i])
-		}
-	}
-	n.Fire()
+	copy(n.ws.in, input)
+	n.forward(n.ws, 1)
 	return nil
 }
 
-// Predict computes a forward pass and returns a prediction
+// Predict computes a forward pass and returns a prediction, calibrated if
+// n has a calibration
 func (n *Neural) Predict(input []float64) []float64 {
 	n.Forward(input)
 
-	outLayer := n.Layers[len(n.Layers)-1]
-	out := make([]float64, len(outLayer.Neurons))
-	for i, neuron := range outLayer.Neurons {
-		out[i] = neuron.Value
-	}
+	out := make([]float64, len(n.ws.output()))
+	copy(out, n.ws.output())
+	n.calibrate(out)
 	return out
 }
 
-// NumWeights returns the number of weights in the network
+// NumWeights returns the number of trainable parameters in the network: the
+// weights, plus the scale and shift of normalized layers
 func (n *Neural) NumWeights() (num int) {
 	for _, l := range n.Layers {
-		for _, n := range l.Neurons {
-			num += len(n.In)
-		}
+		num += len(l.Params())
 	}
 	return
 }
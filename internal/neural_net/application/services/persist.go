@@ -2,22 +2,27 @@ package services
 
 import (
 	"encoding/json"
+	"main/internal/neural_net/application/services/layer"
 	"main/internal/neural_net/domain/entities"
+	"main/internal/neural_net/domain/utils"
 )
 
 // Dump is a neural network dump
 type Dump struct {
 	Config  *entities.Config
 	Weights [][][]float64
+	// Normalization parameters by layer, nil for layers without
+	Norms []*layer.NormState `json:",omitempty"`
+	// Decision thresholds and calibration of the outputs
+	Thresholds  []float64    `json:",omitempty"`
+	Calibration *Calibration `json:",omitempty"`
 }
 
 // ApplyWeights sets the weights from a three-dimensional slice
 func (n *Neural) ApplyWeights(weights [][][]float64) {
 	for i, l := range n.Layers {
-		for j := range l.Neurons {
-			for k := range l.Neurons[j].In {
-				n.Layers[i].Neurons[j].In[k].Weight = weights[i][j][k]
-			}
+		for j := 0; j < l.Outputs; j++ {
+			copy(l.Row(j), weights[i][j])
 		}
 	}
 }
@@ -26,12 +31,10 @@ func (n *Neural) ApplyWeights(weights [][][]float64) {
 func (n Neural) Weights() [][][]float64 {
 	weights := make([][][]float64, len(n.Layers))
 	for i, l := range n.Layers {
-		weights[i] = make([][]float64, len(l.Neurons))
-		for j, n := range l.Neurons {
-			weights[i][j] = make([]float64, len(n.In))
-			for k, in := range n.In {
-				weights[i][j][k] = in.Weight
-			}
+		weights[i] = make([][]float64, l.Outputs)
+		for j := range weights[i] {
+			weights[i][j] = make([]float64, l.Stride())
+			copy(weights[i][j], l.Row(j))
 		}
 	}
 	return weights
@@ -39,16 +42,35 @@ func (n Neural) Weights() [][][]float64 {
 
 // Dump generates a network dump
 func (n *Neural) Dump() *Dump {
-	return &Dump{
-		Config:  n.Config,
-		Weights: n.Weights(),
+	dump := &Dump{
+		Config:      n.Config,
+		Weights:     n.Weights(),
+		Thresholds:  n.Thresholds,
+		Calibration: n.Calibration,
 	}
+	for i, l := range n.Layers {
+		if l.Norm == entities.NormNone {
+			continue
+		}
+		if dump.Norms == nil {
+			dump.Norms = make([]*layer.NormState, len(n.Layers))
+		}
+		dump.Norms[i] = l.NormState()
+	}
+	return dump
 }
 
-// FromDump restores a Neural from a dump
+// FromDump restores a Neural from a dump. Like NewNeural it panics on a
+// config that fails CheckConfig
 func FromDump(dump *Dump) *Neural {
 	n := NewNeural(dump.Config)
 	n.ApplyWeights(dump.Weights)
+	n.Thresholds, n.Calibration = dump.Thresholds, dump.Calibration
+	for i, s := range dump.Norms {
+		if s != nil {
+			n.Layers[i].SetNormState(s)
+		}
+	}
 
 	return n
 }
@@ -58,11 +80,38 @@ func (n *Neural) Marshal() ([]byte, error) {
 	return json.Marshal(n.Dump())
 }
 
-// Unmarshal restores network from a JSON blob
+// Unmarshal restores network from a JSON blob. Custom activations and losses
+// named in the dump must have been registered beforehand
 func Unmarshal(bytes []byte) (*Neural, error) {
 	var dump Dump
 	if err := json.Unmarshal(bytes, &dump); err != nil {
 		return nil, err
 	}
+	if err := CheckConfig(dump.Config); err != nil {
+		return nil, err
+	}
 	return FromDump(&dump), nil
 }
+
+// CheckConfig verifies that every activation and loss named in c has been
+// registered and that its loss settings fit its output layer
+func CheckConfig(c *entities.Config) error {
+	names := []string{c.ActivationName}
+	for _, lc := range c.Layers {
+		names = append(names, lc.ActivationName)
+	}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if _, err := utils.RegisteredActivation(name, 0); err != nil {
+			return err
+		}
+	}
+	if c.LossName != "" {
+		if _, err := RegisteredLoss(c.LossName); err != nil {
+			return err
+		}
+	}
+	return checkLossParams(c)
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"fmt"
+	"main/internal/neural_net/application/services/layer"
+	"main/internal/neural_net/application/services/metrics"
+	"runtime"
+)
+
+// predictorRows is the number of rows a Predictor passes through the
+// network at once in PredictBatch
+const predictorRows = 32
+
+// Predictor is an immutable, compiled network used for inference. Unlike
+// Neural it keeps no state between calls, so Predict and PredictBatch may be
+// called from many goroutines at once
+type Predictor struct {
+	net  *Neural
+	free chan *workspace
+}
+
+// NewPredictor compiles a Predictor from a snapshot of n's weights. Later
+// training of n does not affect the Predictor
+func NewPredictor(n *Neural) *Predictor {
+	config := *n.Config
+	net := &Neural{
+		Layers:      make([]*layer.Layer, len(n.Layers)),
+		Config:      &config,
+		Thresholds:  append([]float64(nil), n.Thresholds...),
+		Calibration: n.Calibration,
+	}
+	for i, l := range n.Layers {
+		net.Layers[i] = l.Clone()
+	}
+
+	return &Predictor{
+		net:  net,
+		free: make(chan *workspace, runtime.GOMAXPROCS(0)),
+	}
+}
+
+// PredictorFromDump compiles a Predictor from a dump. Custom activations and
+// losses named in the dump must have been registered beforehand
+func PredictorFromDump(dump *Dump) (*Predictor, error) {
+	if err := CheckConfig(dump.Config); err != nil {
+		return nil, err
+	}
+	return NewPredictor(FromDump(dump)), nil
+}
+
+// Inputs is the number of inputs expected by the Predictor
+func (p *Predictor) Inputs() int {
+	return p.net.Config.Inputs
+}
+
+// Outputs is the number of outputs produced by the Predictor
+func (p *Predictor) Outputs() int {
+	return p.net.Layers[len(p.net.Layers)-1].Outputs
+}
+
+// Decision returns how the Predictor's network decides classes from its
+// outputs
+func (p *Predictor) Decision() metrics.Decision {
+	return p.net.Decision()
+}
+
+// Predict computes a prediction for input and writes it to out
+func (p *Predictor) Predict(input, out []float64) error {
+	if err := p.check(input, out); err != nil {
+		return err
+	}
+	ws := p.get()
+	copy(ws.in, input)
+	p.net.forward(ws, 1)
+	copy(out, ws.output())
+	p.net.calibrate(out)
+	p.put(ws)
+	return nil
+}
+
+// PredictBatch computes a prediction for each of inputs and writes it to the
+// corresponding element of outs
+func (p *Predictor) PredictBatch(inputs, outs [][]float64) error {
+	if len(inputs) != len(outs) {
+		return fmt.Errorf("Invalid batch size - expected: %d got: %d", len(inputs), len(outs))
+	}
+	for i := range inputs {
+		if err := p.check(inputs[i], outs[i]); err != nil {
+			return err
+		}
+	}
+
+	ws := p.get()
+	numIn, numOut := p.Inputs(), p.Outputs()
+	for start := 0; start < len(inputs); start += predictorRows {
+		rows := min(predictorRows, len(inputs)-start)
+		for r := 0; r < rows; r++ {
+			copy(ws.in[r*numIn:(r+1)*numIn], inputs[start+r])
+		}
+		p.net.forward(ws, rows)
+		out := ws.output()
+		for r := 0; r < rows; r++ {
+			copy(outs[start+r], out[r*numOut:(r+1)*numOut])
+			p.net.calibrate(outs[start+r])
+		}
+	}
+	p.put(ws)
+	return nil
+}
+
+// get takes a workspace from the free list, allocating one if it is empty
+func (p *Predictor) get() *workspace {
+	select {
+	case ws := <-p.free:
+		return ws
+	default:
+		return newWorkspace(p.net, predictorRows)
+	}
+}
+
+// put returns ws to the free list, dropping it if the list is full
+func (p *Predictor) put(ws *workspace) {
+	select {
+	case p.free <- ws:
+	default:
+	}
+}
+
+func (p *Predictor) check(input, out []float64) error {
+	if len(input) != p.Inputs() {
+		return fmt.Errorf("Invalid input dimension - expected: %d got: %d", p.Inputs(), len(input))
+	}
+	if len(out) != p.Outputs() {
+		return fmt.Errorf("Invalid output dimension - expected: %d got: %d", p.Outputs(), len(out))
+	}
+	return nil
+}
@@ -2,67 +2,113 @@ package services
 
 import (
 	"fmt"
+	"main/internal/neural_net/application/services/solver"
 	"main/internal/neural_net/domain/entities"
-	"main/internal/neural_net/domain/utils"
 	"os"
+	"strings"
 	"text/tabwriter"
 	"time"
 )
 
 // StatsPrinter prints training progress
 type StatsPrinter struct {
-	w *tabwriter.Writer
+	w       *tabwriter.Writer
+	solver  solver.Tunable
+	metrics []entities.MetricType
 }
 
-// NewStatsPrinter creates a StatsPrinter
-func NewStatsPrinter() *StatsPrinter {
-	return &StatsPrinter{tabwriter.NewWriter(os.Stdout, 16, 0, 3, ' ', 0)}
+// NewStatsPrinter creates a StatsPrinter printing the given metrics of the
+// validation examples
+func NewStatsPrinter(metrics ...entities.MetricType) *StatsPrinter {
+	return &StatsPrinter{w: tabwriter.NewWriter(os.Stdout, 16, 0, 3, ' ', 0), metrics: metrics}
 }
 
-// Init initializes printer
-func (p *StatsPrinter) Init(n *Neural) {
-	fmt.Fprintf(p.w, "Epochs\tElapsed\tLoss (%s)\t", n.Config.Loss)
-	if n.Config.Mode == entities.ModeMultiClass {
-		fmt.Fprintf(p.w, "Accuracy\t\n---\t---\t---\t---\t\n")
-	} else {
-		fmt.Fprintf(p.w, "\n---\t---\t---\t\n")
+// Init initializes printer. Without metrics it prints the loss, and the
+// accuracy for ModeMultiClass. The learning rate of s is printed if it has one
+func (p *StatsPrinter) Init(n *Neural, s solver.Solver) {
+	p.solver, _ = s.(solver.Tunable)
+	if len(p.metrics) == 0 {
+		p.metrics = []entities.MetricType{entities.MetricLoss}
+		if n.Config.Mode == entities.ModeMultiClass {
+			p.metrics = append(p.metrics, entities.MetricAccuracy)
+		}
+	}
+	fmt.Fprintf(p.w, "Epochs\tElapsed\t")
+	for _, m := range p.metrics {
+		if m == entities.MetricLoss {
+			fmt.Fprintf(p.w, "Loss (%s)\t", lossName(n.Config))
+			continue
+		}
+		fmt.Fprintf(p.w, "%s\t", m)
 	}
+	columns := 2 + len(p.metrics)
+	if p.solver != nil {
+		fmt.Fprintf(p.w, "LR\t")
+		columns++
+	}
+	fmt.Fprintf(p.w, "\n%s\n", strings.Repeat("---\t", columns))
 }
 
 // PrintProgress prints the current state of training
 func (p *StatsPrinter) PrintProgress(n *Neural, validation Examples, elapsed time.Duration, iteration int) {
-	fmt.Fprintf(p.w, "%d\t%s\t%.4f\t%s\n",
-		iteration,
-		elapsed.String(),
-		CrossValidate(n, validation),
-		FormatAccuracy(n, validation))
+	p.print(iteration, elapsed, func(m entities.MetricType) float64 {
+		return Evaluate(n, validation, m)
+	})
+}
+
+func (p *StatsPrinter) print(iteration int, elapsed time.Duration, metric func(m entities.MetricType) float64) {
+	fmt.Fprintf(p.w, "%d\t%s\t", iteration, elapsed.String())
+	for _, m := range p.metrics {
+		if m == entities.MetricAccuracy {
+			fmt.Fprintf(p.w, "%.2f\t", metric(m))
+			continue
+		}
+		fmt.Fprintf(p.w, "%.4f\t", metric(m))
+	}
+	fmt.Fprintf(p.w, "%s\n", p.formatRate())
 	p.w.Flush()
 }
 
-func FormatAccuracy(n *Neural, validation Examples) string {
-	if n.Config.Mode == entities.ModeMultiClass {
-		return fmt.Sprintf("%.2f\t", Accuracy(n, validation))
+// printerCallback prints the progress of training every verbosity epochs,
+// given validation examples
+type printerCallback struct {
+	CallbackFuncs
+	printer   *StatsPrinter
+	verbosity int
+	started   bool
+}
+
+func (c *printerCallback) OnEpochStart(p *Progress) error {
+	if c.started {
+		return nil
 	}
-	return ""
+	c.started = true
+	c.printer.Init(p.Network, p.Solver)
+	return nil
 }
 
-func Accuracy(n *Neural, validation Examples) float64 {
-	correct := 0
-	for _, e := range validation {
-		est := n.Predict(e.Input)
-		if utils.ArgMax(e.Response) == utils.ArgMax(est) {
-			correct++
-		}
+func (c *printerCallback) OnEpochEnd(p *Progress) error {
+	if p.Epoch%c.verbosity == 0 && p.validates() {
+		c.printer.print(p.Epoch, p.Elapsed, p.Metric)
 	}
-	return float64(correct) / float64(len(validation))
+	return nil
 }
 
-func CrossValidate(n *Neural, validation Examples) float64 {
-	predictions, responses := make([][]float64, len(validation)), make([][]float64, len(validation))
-	for i := 0; i < len(validation); i++ {
-		predictions[i] = n.Predict(validation[i].Input)
-		responses[i] = validation[i].Response
+func (p *StatsPrinter) formatRate() string {
+	if p.solver == nil {
+		return ""
 	}
+	return fmt.Sprintf("%.3g\t", p.solver.LearningRate())
+}
 
-	return GetLoss(n.Config.Loss).F(predictions, responses)
+func FormatAccuracy(n *Neural, validation Examples) string {
+	if n.Config.Mode == entities.ModeMultiClass {
+		return fmt.Sprintf("%.2f\t", Accuracy(n, validation))
+	}
+	return ""
+}
+
+func CrossValidate(n *Neural, validation Examples) float64 {
+	predictions, responses := predictAll(n, validation)
+	return configLoss(n.Config).F(predictions, responses) + n.penalty()
 }
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"main/internal/neural_net/domain/utils"
+	"math"
+)
+
+// penalty is the L1/L2 penalty of the weights of n, excluding biases
+func (n *Neural) penalty() float64 {
+	c := n.Config
+	if c.L1 == 0 && c.L2 == 0 {
+		return 0
+	}
+	var l1, l2 float64
+	for _, l := range n.Layers {
+		for k, w := range l.W {
+			if l.IsBias(k) {
+				continue
+			}
+			l1 += math.Abs(w)
+			l2 += w * w
+		}
+	}
+	return c.L1*l1 + c.L2*l2/2
+}
+
+// regularize adds scale times the gradient of the L1/L2 penalty to grads,
+// which are shaped like the weights of n
+func (n *Neural) regularize(grads [][]float64, scale float64) {
+	c := n.Config
+	if c.L1 == 0 && c.L2 == 0 {
+		return
+	}
+	for i, l := range n.Layers {
+		g := grads[i]
+		for k, w := range l.W {
+			if l.IsBias(k) {
+				continue
+			}
+			g[k] += scale * (c.L1*utils.Sgn(w) + c.L2*w)
+		}
+	}
+}
+
+// decay applies decoupled weight decay to parameter k of layer i, given its
+// value before the solver update
+func (n *Neural) decay(i, k int, w float64) {
+	if n.Config.WeightDecay == 0 || !n.Layers[i].Penalized(k) {
+		return
+	}
+	n.Layers[i].W[k] -= n.Config.WeightDecay * w
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/internal/neural_net/application/services/solver"
+	"main/internal/neural_net/domain/entities"
+	"main/internal/neural_net/domain/utils"
+	"main/pkg/workerpool"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// SearchSpace declares the values tried for every hyperparameter. Empty
+// dimensions keep the value of the base configurations
+type SearchSpace struct {
+	Layouts       [][]int
+	Activations   []entities.ActivationType
+	LearningRates []float64
+	BatchSizes    []int
+}
+
+// Trial is a point of a SearchSpace
+type Trial struct {
+	Layout       []int
+	Activation   entities.ActivationType
+	LearningRate float64
+	BatchSize    int
+}
+
+// String describes the hyperparameters of t
+func (t Trial) String() string {
+	return fmt.Sprintf("layout %v, activation %s, lr %.3g, batch %d", t.Layout, t.Activation, t.LearningRate, t.BatchSize)
+}
+
+// TrialResult is the outcome of a trial
+type TrialResult struct {
+	Trial
+	// Metric of the validation examples, NaN if training failed
+	Score float64
+	// Epochs trained
+	Epochs int
+	// Why training failed, if it did
+	Err error
+
+	n       *Neural
+	trainer *BatchTrainer
+	// Rounds of SuccessiveHalving survived
+	round int
+}
+
+// SearchResult is the outcome of a search
+type SearchResult struct {
+	// Results of all trials, best first
+	Leaderboard []TrialResult
+	// Network of the best trial
+	Best *Dump
+}
+
+// Search tunes hyperparameters by training a network per trial, scoring it
+// on the validation examples
+type Search struct {
+	// Base configurations, trials override them. Its per-layer settings only
+	// apply to trials with as many layers, the trial activation replacing
+	// that of the hidden layers when the search space has activations
+	Config  *entities.Config
+	Trainer entities.TrainerConfig
+	// Solver returns the solver of a trial, 0 selects the default rate
+	// (default Adam)
+	Solver func(learningRate float64) solver.Solver
+	Space  SearchSpace
+	// Metric scoring trials, the loss by default
+	Metric entities.MetricType
+	// Epochs every trial trains for, the final round of SuccessiveHalving
+	Epochs int
+	// Trials trained concurrently (default 1)
+	Workers int
+}
+
+// Grid tries every combination of the search space
+func (s *Search) Grid(ctx context.Context, train, validation Examples) (*SearchResult, error) {
+	return s.run(ctx, s.grid(), train, validation)
+}
+
+// Random tries the given number of combinations, each value drawn uniformly
+// from its dimension with rng
+func (s *Search) Random(ctx context.Context, train, validation Examples, trials int, rng *rand.Rand) (*SearchResult, error) {
+	if rng == nil {
+		return nil, errors.New("random search needs a random source")
+	}
+	return s.run(ctx, s.sample(trials, rng), train, validation)
+}
+
+// SuccessiveHalving starts with candidates random combinations, or the whole
+// grid if candidates is zero, and keeps training the best 1/eta of them (by
+// default a third) for eta times more epochs, until the last round trains the
+// survivors for Epochs. The leaderboard ranks trials by the round they
+// reached, then by score
+func (s *Search) SuccessiveHalving(ctx context.Context, train, validation Examples, candidates, eta int,
+	rng *rand.Rand) (*SearchResult, error) {
+	eta = utils.Iparam(eta, 3)
+	trials := s.grid()
+	if candidates > 0 {
+		if rng == nil {
+			return nil, errors.New("sampling candidates needs a random source")
+		}
+		trials = s.sample(candidates, rng)
+	}
+	rounds := 1
+	for n := len(trials); n > eta; n = (n + eta - 1) / eta {
+		rounds++
+	}
+
+	survivors, retired := results(trials), []TrialResult(nil)
+	for r := 0; ; r++ {
+		epochs := s.Epochs / int(math.Pow(float64(eta), float64(rounds-1-r)))
+		if err := s.train(ctx, survivors, train, validation, utils.Iparam(epochs, 1)); err != nil {
+			return nil, err
+		}
+		if r == rounds-1 {
+			return s.result(append(survivors, retired...)), nil
+		}
+		s.rank(survivors)
+		keep := (len(survivors) + eta - 1) / eta
+		retired = append(survivors[keep:len(survivors):len(survivors)], retired...)
+		survivors = survivors[:keep:keep]
+		for i := range survivors {
+			survivors[i].round++
+		}
+	}
+}
+
+func (s *Search) run(ctx context.Context, trials []Trial, train, validation Examples) (*SearchResult, error) {
+	res := results(trials)
+	if err := s.train(ctx, res, train, validation, s.Epochs); err != nil {
+		return nil, err
+	}
+	return s.result(res), nil
+}
+
+func results(trials []Trial) []TrialResult {
+	res := make([]TrialResult, len(trials))
+	for i, t := range trials {
+		res[i] = TrialResult{Trial: t}
+	}
+	return res
+}
+
+// train trains every trial of results up to epochs concurrently. Trials
+// failing do not stop the search, only the cancellation of ctx does
+func (s *Search) train(ctx context.Context, results []TrialResult, train, validation Examples, epochs int) error {
+	if len(validation) == 0 {
+		return errors.New("search needs validation examples")
+	}
+	pool := workerpool.NewWorkerPool(utils.Iparam(s.Workers, 1))
+	pool.Run()
+	defer pool.Stop()
+	wg := sync.WaitGroup{}
+	for i := range results {
+		wg.Add(1)
+		r := &results[i]
+		pool.AddTask(func() {
+			defer wg.Done()
+			s.trial(ctx, r, train, validation, epochs)
+		})
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// trial trains the network of r up to epochs, building it first, or carrying
+// on from the epochs it already trained
+func (s *Search) trial(ctx context.Context, r *TrialResult, train, validation Examples, epochs int) {
+	if r.n == nil {
+		c := *s.Config
+		c.Layout, c.Activation, c.ActivationName = r.Layout, r.Activation, ""
+		c.Layers = s.layers(r.Trial)
+		if err := CheckConfig(&c); err != nil {
+			r.Score, r.Err = math.NaN(), err
+			return
+		}
+		tc := s.Trainer
+		tc.BatchSize = r.BatchSize
+		r.n = NewNeural(&c)
+		r.trainer = NewTrainerWithConfig(s.solver(r.LearningRate), tc)
+	} else {
+		r.trainer.resumed = true
+	}
+	result, err := r.trainer.Train(ctx, r.n, train, validation, epochs)
+	r.Score, r.Err = math.NaN(), err
+	if result != nil {
+		r.Epochs = result.Epochs
+	}
+	if err == nil {
+		r.Score = Evaluate(r.n, validation, s.Metric)
+	}
+}
+
+// layers returns the per-layer settings of the base configurations for t,
+// none if t has a different number of layers
+func (s *Search) layers(t Trial) []entities.LayerConfig {
+	if len(t.Layout) == 0 || len(s.Config.Layers) != len(t.Layout) {
+		return nil
+	}
+	layers := append([]entities.LayerConfig(nil), s.Config.Layers...)
+	if len(s.Space.Activations) > 0 {
+		for i := range layers[:len(layers)-1] {
+			layers[i].Activation, layers[i].ActivationName = t.Activation, ""
+		}
+	}
+	return layers
+}
+
+// solver returns the solver of a trial
+func (s *Search) solver(learningRate float64) solver.Solver {
+	if s.Solver == nil {
+		return solver.NewAdam(learningRate, 0, 0, 0)
+	}
+	return s.Solver(learningRate)
+}
+
+// grid returns every combination of the search space
+func (s *Search) grid() []Trial {
+	layouts, activations, rates, sizes := s.dimensions()
+	var trials []Trial
+	for _, l := range layouts {
+		for _, a := range activations {
+			for _, lr := range rates {
+				for _, bs := range sizes {
+					trials = append(trials, Trial{Layout: l, Activation: a, LearningRate: lr, BatchSize: bs})
+				}
+			}
+		}
+	}
+	return trials
+}
+
+// sample draws n combinations of the search space from rng
+func (s *Search) sample(n int, rng *rand.Rand) []Trial {
+	layouts, activations, rates, sizes := s.dimensions()
+	trials := make([]Trial, n)
+	for i := range trials {
+		trials[i] = Trial{
+			Layout:       layouts[rng.Intn(len(layouts))],
+			Activation:   activations[rng.Intn(len(activations))],
+			LearningRate: rates[rng.Intn(len(rates))],
+			BatchSize:    sizes[rng.Intn(len(sizes))],
+		}
+	}
+	return trials
+}
+
+// dimensions returns the values of every dimension, falling back to the
+// base configurations
+func (s *Search) dimensions() ([][]int, []entities.ActivationType, []float64, []int) {
+	layouts, activations, rates, sizes := s.Space.Layouts, s.Space.Activations, s.Space.LearningRates, s.Space.BatchSizes
+	if len(layouts) == 0 {
+		layouts = [][]int{s.Config.Layout}
+	}
+	if len(activations) == 0 {
+		activations = []entities.ActivationType{s.Config.Activation}
+	}
+	if len(rates) == 0 {
+		rates = []float64{0}
+	}
+	if len(sizes) == 0 {
+		sizes = []int{utils.Iparam(s.Trainer.BatchSize, 1)}
+	}
+	return layouts, activations, rates, sizes
+}
+
+// rank sorts results best first, failed trials last
+func (s *Search) rank(results []TrialResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i].Score, results[j].Score
+		if math.IsNaN(b) {
+			return !math.IsNaN(a)
+		}
+		return improves(s.Metric, a, b, 0)
+	})
+}
+
+func (s *Search) result(results []TrialResult) *SearchResult {
+	s.rank(results)
+	// Trials that reached later rounds come first
+	sort.SliceStable(results, func(i, j int) bool { return results[i].round > results[j].round })
+	res := &SearchResult{Leaderboard: results}
+	if len(results) > 0 && results[0].Err == nil {
+		res.Best = results[0].n.Dump()
+	}
+	return res
+}
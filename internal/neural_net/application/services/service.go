@@ -1,16 +1,18 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"main/config"
 	"main/internal/neural_net/application/services/layer/neuron/synapse"
 	"main/internal/neural_net/application/services/solver"
 	"main/internal/neural_net/domain/entities"
+	nnErrors "main/internal/neural_net/domain/errors"
 	"main/internal/neural_net/domain/ports"
 	"main/pkg/logger"
 	"main/pkg/utils/typeconv"
-	"math/rand"
+	"sync/atomic"
 )
 
 var (
@@ -23,9 +25,10 @@ const (
 
 // serviceNeuralNet Auth Service
 type serviceNeuralNet struct {
-	cfg    *config.Config
-	pgRepo ports.IPostgresqlRepository
-	logger logger.Logger
+	cfg       *config.Config
+	pgRepo    ports.IPostgresqlRepository
+	logger    logger.Logger
+	predictor atomic.Value
 }
 
 // NewNeuralNetService Auth domain service constructor
@@ -33,21 +36,41 @@ func NewNeuralNetService(cfg *config.Config, pgRepo ports.IPostgresqlRepository,
 	return &serviceNeuralNet{cfg: cfg, pgRepo: pgRepo, logger: logger}
 }
 
-func (w *serviceNeuralNet) Train() {
-	rand.Seed(0)
+// Train trains the model, stopping between two examples with the error of
+// ctx once it is done. Predict serves the model once it is trained
+func (w *serviceNeuralNet) Train(ctx context.Context) error {
+	// Fixed seeds keep the trained model the same from one run to the next,
+	// whatever else draws random numbers
 	n := NewNeural(&entities.Config{
 		Inputs:     4,
 		Layout:     []int{5, 1}, // Sufficient for modeling (AND+OR) - with 5-6 neuron always converges
 		Activation: entities.ActivationSigmoid,
 		Mode:       entities.ModeBinary,
 		Weight:     synapse.NewUniform(1, 0),
+		Seed:       1,
 		Bias:       true,
 	})
 	permutations := GetData()
 
-	trainer := NewTrainer(solver.NewSGD(0.1, 0.1, 1e-6, false), 50)
-	trainer.Train(n, permutations, permutations, 10000)
+	trainer := NewTrainer(solver.NewSGD(0.1, 0.1, 1e-6, false), 50).WithSeed(1)
+	if _, err := trainer.Train(ctx, n, permutations, permutations, 10000); err != nil {
+		return err
+	}
 	fmt.Println(n.Predict(permutations[0].Input))
+	w.predictor.Store(NewPredictor(n))
+	return nil
+}
+
+func (w *serviceNeuralNet) Predict(input []float64) ([]float64, error) {
+	p, ok := w.predictor.Load().(*Predictor)
+	if !ok {
+		return nil, nnErrors.ErrNotTrained
+	}
+	out := make([]float64, p.Outputs())
+	if err := p.Predict(input, out); err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
 func GetData() (Linedata Examples) {
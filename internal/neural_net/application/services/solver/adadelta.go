@@ -0,0 +1,70 @@
+package solver
+
+import (
+	"encoding/json"
+	"main/internal/neural_net/domain/utils"
+	"math"
+)
+
+// Adadelta is an Adadelta solver
+type Adadelta struct {
+	lr      float64
+	rho     float64
+	epsilon float64
+
+	g, d []float64
+}
+
+// NewAdadelta returns a new Adadelta solver. The learning rate only scales
+// the adaptive step and defaults to 1
+func NewAdadelta(lr, rho, epsilon float64) *Adadelta {
+	return &Adadelta{
+		lr:      utils.Fparam(lr, 1),
+		rho:     utils.Fparam(rho, 0.95),
+		epsilon: utils.Fparam(epsilon, 1e-6),
+	}
+}
+
+// Init initializes vectors using number of weights in network
+func (o *Adadelta) Init(size int) {
+	o.g, o.d = make([]float64, size), make([]float64, size)
+}
+
+// Update returns the update for a given weight
+func (o *Adadelta) Update(value, gradient float64, t, idx int) float64 {
+	o.g[idx] = o.rho*o.g[idx] + (1-o.rho)*gradient*gradient
+	delta := -math.Sqrt(o.d[idx]+o.epsilon) / math.Sqrt(o.g[idx]+o.epsilon) * gradient
+	o.d[idx] = o.rho*o.d[idx] + (1-o.rho)*delta*delta
+
+	return o.lr * delta
+}
+
+// LearningRate returns the learning rate
+func (o *Adadelta) LearningRate() float64 {
+	return o.lr
+}
+
+// SetLearningRate sets the learning rate
+func (o *Adadelta) SetLearningRate(lr float64) {
+	o.lr = lr
+}
+
+type adadeltaState struct {
+	LR, Rho, Epsilon float64
+	G, D             []float64
+}
+
+// MarshalJSON encodes the state of the solver
+func (o *Adadelta) MarshalJSON() ([]byte, error) {
+	return json.Marshal(adadeltaState{o.lr, o.rho, o.epsilon, o.g, o.d})
+}
+
+// UnmarshalJSON restores the state of the solver
+func (o *Adadelta) UnmarshalJSON(b []byte) error {
+	var s adadeltaState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	o.lr, o.rho, o.epsilon, o.g, o.d = s.LR, s.Rho, s.Epsilon, s.G, s.D
+	return nil
+}
@@ -0,0 +1,65 @@
+package solver
+
+import (
+	"encoding/json"
+	"main/internal/neural_net/domain/utils"
+	"math"
+)
+
+// Adagrad is an Adagrad solver
+type Adagrad struct {
+	lr      float64
+	epsilon float64
+
+	g []float64
+}
+
+// NewAdagrad returns a new Adagrad solver
+func NewAdagrad(lr, epsilon float64) *Adagrad {
+	return &Adagrad{
+		lr:      utils.Fparam(lr, 0.01),
+		epsilon: utils.Fparam(epsilon, 1e-8),
+	}
+}
+
+// Init initializes vectors using number of weights in network
+func (o *Adagrad) Init(size int) {
+	o.g = make([]float64, size)
+}
+
+// Update returns the update for a given weight
+func (o *Adagrad) Update(value, gradient float64, t, idx int) float64 {
+	o.g[idx] += gradient * gradient
+
+	return -o.lr * gradient / (math.Sqrt(o.g[idx]) + o.epsilon)
+}
+
+// LearningRate returns the learning rate
+func (o *Adagrad) LearningRate() float64 {
+	return o.lr
+}
+
+// SetLearningRate sets the learning rate
+func (o *Adagrad) SetLearningRate(lr float64) {
+	o.lr = lr
+}
+
+type adagradState struct {
+	LR, Epsilon float64
+	G           []float64
+}
+
+// MarshalJSON encodes the state of the solver
+func (o *Adagrad) MarshalJSON() ([]byte, error) {
+	return json.Marshal(adagradState{o.lr, o.epsilon, o.g})
+}
+
+// UnmarshalJSON restores the state of the solver
+func (o *Adagrad) UnmarshalJSON(b []byte) error {
+	var s adagradState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	o.lr, o.epsilon, o.g = s.LR, s.Epsilon, s.G
+	return nil
+}
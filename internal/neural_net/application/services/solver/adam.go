@@ -1,6 +1,7 @@
 package solver
 
 import (
+	"encoding/json"
 	"main/internal/neural_net/domain/utils"
 	"math"
 )
@@ -39,3 +40,33 @@ func (o *Adam) Update(value, gradient float64, t, idx int) float64 {
 
 	return -lrt * (o.m[idx] / (math.Sqrt(o.v[idx]) + o.epsilon))
 }
+
+// LearningRate returns the learning rate
+func (o *Adam) LearningRate() float64 {
+	return o.lr
+}
+
+// SetLearningRate sets the learning rate
+func (o *Adam) SetLearningRate(lr float64) {
+	o.lr = lr
+}
+
+type adamState struct {
+	LR, Beta, Beta2, Epsilon float64
+	M, V                     []float64
+}
+
+// MarshalJSON encodes the state of the solver
+func (o *Adam) MarshalJSON() ([]byte, error) {
+	return json.Marshal(adamState{o.lr, o.beta, o.beta2, o.epsilon, o.m, o.v})
+}
+
+// UnmarshalJSON restores the state of the solver
+func (o *Adam) UnmarshalJSON(b []byte) error {
+	var s adamState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	o.lr, o.beta, o.beta2, o.epsilon, o.m, o.v = s.LR, s.Beta, s.Beta2, s.Epsilon, s.M, s.V
+	return nil
+}
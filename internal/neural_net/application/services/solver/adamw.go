@@ -0,0 +1,46 @@
+package solver
+
+import (
+	"encoding/json"
+	"main/internal/neural_net/domain/utils"
+)
+
+// AdamW is Adam with decoupled weight decay
+type AdamW struct {
+	Adam
+	decay float64
+}
+
+// NewAdamW returns a new AdamW solver. Every update also shrinks the weight
+// by lr*decay, independently of its gradient
+func NewAdamW(lr, beta, beta2, epsilon, decay float64) *AdamW {
+	return &AdamW{
+		Adam:  *NewAdam(lr, beta, beta2, epsilon),
+		decay: utils.Fparam(decay, 0.01),
+	}
+}
+
+// Update returns the update for a given weight
+func (o *AdamW) Update(value, gradient float64, t, idx int) float64 {
+	return o.Adam.Update(value, gradient, t, idx) - o.lr*o.decay*value
+}
+
+type adamwState struct {
+	adamState
+	Decay float64
+}
+
+// MarshalJSON encodes the state of the solver
+func (o *AdamW) MarshalJSON() ([]byte, error) {
+	return json.Marshal(adamwState{adamState{o.lr, o.beta, o.beta2, o.epsilon, o.m, o.v}, o.decay})
+}
+
+// UnmarshalJSON restores the state of the solver
+func (o *AdamW) UnmarshalJSON(b []byte) error {
+	var s adamwState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	o.lr, o.beta, o.beta2, o.epsilon, o.m, o.v, o.decay = s.LR, s.Beta, s.Beta2, s.Epsilon, s.M, s.V, s.Decay
+	return nil
+}
@@ -0,0 +1,74 @@
+package solver
+
+import (
+	"encoding/json"
+	"main/internal/neural_net/domain/utils"
+	"math"
+)
+
+// AMSGrad is Adam using the maximum of past second moments, which keeps
+// the effective learning rate from growing
+type AMSGrad struct {
+	lr      float64
+	beta    float64
+	beta2   float64
+	epsilon float64
+
+	v, vmax, m []float64
+}
+
+// NewAMSGrad returns a new AMSGrad solver
+func NewAMSGrad(lr, beta, beta2, epsilon float64) *AMSGrad {
+	return &AMSGrad{
+		lr:      utils.Fparam(lr, 0.001),
+		beta:    utils.Fparam(beta, 0.9),
+		beta2:   utils.Fparam(beta2, 0.999),
+		epsilon: utils.Fparam(epsilon, 1e-8),
+	}
+}
+
+// Init initializes vectors using number of weights in network
+func (o *AMSGrad) Init(size int) {
+	o.v, o.vmax, o.m = make([]float64, size), make([]float64, size), make([]float64, size)
+}
+
+// Update returns the update for a given weight
+func (o *AMSGrad) Update(value, gradient float64, t, idx int) float64 {
+	lrt := o.lr * (math.Sqrt(1.0 - math.Pow(o.beta2, float64(t)))) /
+		(1.0 - math.Pow(o.beta, float64(t)))
+	o.m[idx] = o.beta*o.m[idx] + (1.0-o.beta)*gradient
+	o.v[idx] = o.beta2*o.v[idx] + (1.0-o.beta2)*gradient*gradient
+	o.vmax[idx] = math.Max(o.vmax[idx], o.v[idx])
+
+	return -lrt * (o.m[idx] / (math.Sqrt(o.vmax[idx]) + o.epsilon))
+}
+
+// LearningRate returns the learning rate
+func (o *AMSGrad) LearningRate() float64 {
+	return o.lr
+}
+
+// SetLearningRate sets the learning rate
+func (o *AMSGrad) SetLearningRate(lr float64) {
+	o.lr = lr
+}
+
+type amsgradState struct {
+	adamState
+	VMax []float64
+}
+
+// MarshalJSON encodes the state of the solver
+func (o *AMSGrad) MarshalJSON() ([]byte, error) {
+	return json.Marshal(amsgradState{adamState{o.lr, o.beta, o.beta2, o.epsilon, o.m, o.v}, o.vmax})
+}
+
+// UnmarshalJSON restores the state of the solver
+func (o *AMSGrad) UnmarshalJSON(b []byte) error {
+	var s amsgradState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	o.lr, o.beta, o.beta2, o.epsilon, o.m, o.v, o.vmax = s.LR, s.Beta, s.Beta2, s.Epsilon, s.M, s.V, s.VMax
+	return nil
+}
@@ -0,0 +1,69 @@
+package solver
+
+import (
+	"encoding/json"
+	"main/internal/neural_net/domain/utils"
+	"math"
+)
+
+// Nadam is Adam with Nesterov momentum
+type Nadam struct {
+	lr      float64
+	beta    float64
+	beta2   float64
+	epsilon float64
+
+	v, m []float64
+}
+
+// NewNadam returns a new Nadam solver
+func NewNadam(lr, beta, beta2, epsilon float64) *Nadam {
+	return &Nadam{
+		lr:      utils.Fparam(lr, 0.001),
+		beta:    utils.Fparam(beta, 0.9),
+		beta2:   utils.Fparam(beta2, 0.999),
+		epsilon: utils.Fparam(epsilon, 1e-8),
+	}
+}
+
+// Init initializes vectors using number of weights in network
+func (o *Nadam) Init(size int) {
+	o.v, o.m = make([]float64, size), make([]float64, size)
+}
+
+// Update returns the update for a given weight
+func (o *Nadam) Update(value, gradient float64, t, idx int) float64 {
+	o.m[idx] = o.beta*o.m[idx] + (1.0-o.beta)*gradient
+	o.v[idx] = o.beta2*o.v[idx] + (1.0-o.beta2)*gradient*gradient
+
+	bias := 1.0 - math.Pow(o.beta, float64(t))
+	m := o.beta*o.m[idx]/bias + (1.0-o.beta)*gradient/bias
+	v := o.v[idx] / (1.0 - math.Pow(o.beta2, float64(t)))
+
+	return -o.lr * m / (math.Sqrt(v) + o.epsilon)
+}
+
+// LearningRate returns the learning rate
+func (o *Nadam) LearningRate() float64 {
+	return o.lr
+}
+
+// SetLearningRate sets the learning rate
+func (o *Nadam) SetLearningRate(lr float64) {
+	o.lr = lr
+}
+
+// MarshalJSON encodes the state of the solver
+func (o *Nadam) MarshalJSON() ([]byte, error) {
+	return json.Marshal(adamState{o.lr, o.beta, o.beta2, o.epsilon, o.m, o.v})
+}
+
+// UnmarshalJSON restores the state of the solver
+func (o *Nadam) UnmarshalJSON(b []byte) error {
+	var s adamState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	o.lr, o.beta, o.beta2, o.epsilon, o.m, o.v = s.LR, s.Beta, s.Beta2, s.Epsilon, s.M, s.V
+	return nil
+}
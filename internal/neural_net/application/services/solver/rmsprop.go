@@ -0,0 +1,67 @@
+package solver
+
+import (
+	"encoding/json"
+	"main/internal/neural_net/domain/utils"
+	"math"
+)
+
+// RMSProp is an RMSProp solver
+type RMSProp struct {
+	lr      float64
+	rho     float64
+	epsilon float64
+
+	v []float64
+}
+
+// NewRMSProp returns a new RMSProp solver
+func NewRMSProp(lr, rho, epsilon float64) *RMSProp {
+	return &RMSProp{
+		lr:      utils.Fparam(lr, 0.001),
+		rho:     utils.Fparam(rho, 0.9),
+		epsilon: utils.Fparam(epsilon, 1e-8),
+	}
+}
+
+// Init initializes vectors using number of weights in network
+func (o *RMSProp) Init(size int) {
+	o.v = make([]float64, size)
+}
+
+// Update returns the update for a given weight
+func (o *RMSProp) Update(value, gradient float64, t, idx int) float64 {
+	o.v[idx] = o.rho*o.v[idx] + (1-o.rho)*gradient*gradient
+
+	return -o.lr * gradient / (math.Sqrt(o.v[idx]) + o.epsilon)
+}
+
+// LearningRate returns the learning rate
+func (o *RMSProp) LearningRate() float64 {
+	return o.lr
+}
+
+// SetLearningRate sets the learning rate
+func (o *RMSProp) SetLearningRate(lr float64) {
+	o.lr = lr
+}
+
+type rmspropState struct {
+	LR, Rho, Epsilon float64
+	V                []float64
+}
+
+// MarshalJSON encodes the state of the solver
+func (o *RMSProp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rmspropState{o.lr, o.rho, o.epsilon, o.v})
+}
+
+// UnmarshalJSON restores the state of the solver
+func (o *RMSProp) UnmarshalJSON(b []byte) error {
+	var s rmspropState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	o.lr, o.rho, o.epsilon, o.v = s.LR, s.Rho, s.Epsilon, s.V
+	return nil
+}
@@ -0,0 +1,306 @@
+package solver
+
+import (
+	"encoding/json"
+	"fmt"
+	"main/internal/neural_net/domain/utils"
+	"math"
+)
+
+// Tunable is a solver whose learning rate may change during training
+type Tunable interface {
+	Solver
+	LearningRate() float64
+	SetLearningRate(lr float64)
+}
+
+// Schedule gives the learning rate of an epoch, counted from 1, given the
+// base learning rate of the solver
+type Schedule interface {
+	Rate(base float64, epoch int) float64
+}
+
+// Observer is implemented by schedules driven by the validation loss. Trainers
+// report the loss at the end of every epoch when given validation examples
+type Observer interface {
+	Observe(loss float64)
+}
+
+// Scheduled wraps a solver, setting its learning rate from a schedule at
+// the start of every epoch
+type Scheduled struct {
+	Tunable
+	schedule Schedule
+	base     float64
+	epoch    int
+}
+
+// NewScheduled returns s driven by schedule, starting from the current
+// learning rate of s
+func NewScheduled(s Tunable, schedule Schedule) *Scheduled {
+	return &Scheduled{
+		Tunable:  s,
+		schedule: schedule,
+		base:     s.LearningRate(),
+	}
+}
+
+// Schedule returns the schedule of o
+func (o *Scheduled) Schedule() Schedule {
+	return o.schedule
+}
+
+// Init initializes vectors using number of weights in network
+func (o *Scheduled) Init(size int) {
+	o.Tunable.Init(size)
+	o.epoch = 1
+	o.SetLearningRate(o.schedule.Rate(o.base, 1))
+}
+
+// Update returns the update for a given weight
+func (o *Scheduled) Update(value, gradient float64, iteration, idx int) float64 {
+	if iteration != o.epoch {
+		o.epoch = iteration
+		o.SetLearningRate(o.schedule.Rate(o.base, iteration))
+	}
+	return o.Tunable.Update(value, gradient, iteration, idx)
+}
+
+type scheduledState struct {
+	Solver   json.RawMessage
+	Schedule json.RawMessage `json:",omitempty"`
+	Base     float64
+	Epoch    int
+}
+
+// MarshalJSON encodes the state of the wrapped solver and of the schedule.
+// It fails if the wrapped solver is not Stateful
+func (o *Scheduled) MarshalJSON() ([]byte, error) {
+	inner, ok := o.Tunable.(json.Marshaler)
+	if !ok {
+		return nil, fmt.Errorf("solver %T has no state to save", o.Tunable)
+	}
+	s := scheduledState{Base: o.base, Epoch: o.epoch}
+	var err error
+	if s.Solver, err = inner.MarshalJSON(); err != nil {
+		return nil, err
+	}
+	if m, ok := o.schedule.(json.Marshaler); ok {
+		if s.Schedule, err = m.MarshalJSON(); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON restores the state of the wrapped solver and of the schedule
+func (o *Scheduled) UnmarshalJSON(b []byte) error {
+	var s scheduledState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(s.Solver, o.Tunable); err != nil {
+		return err
+	}
+	if u, ok := o.schedule.(json.Unmarshaler); ok && s.Schedule != nil {
+		if err := u.UnmarshalJSON(s.Schedule); err != nil {
+			return err
+		}
+	}
+	o.base, o.epoch = s.Base, s.Epoch
+	return nil
+}
+
+// StepDecay multiplies the learning rate by factor every step epochs
+type StepDecay struct {
+	step   int
+	factor float64
+}
+
+// NewStepDecay returns a StepDecay schedule
+func NewStepDecay(step int, factor float64) *StepDecay {
+	return &StepDecay{
+		step:   utils.Iparam(step, 10),
+		factor: utils.Fparam(factor, 0.5),
+	}
+}
+
+// Rate returns the learning rate of epoch
+func (s *StepDecay) Rate(base float64, epoch int) float64 {
+	return base * math.Pow(s.factor, float64((epoch-1)/s.step))
+}
+
+// ExponentialDecay multiplies the learning rate by gamma every epoch
+type ExponentialDecay struct {
+	gamma float64
+}
+
+// NewExponentialDecay returns an ExponentialDecay schedule
+func NewExponentialDecay(gamma float64) *ExponentialDecay {
+	return &ExponentialDecay{gamma: utils.Fparam(gamma, 0.95)}
+}
+
+// Rate returns the learning rate of epoch
+func (s *ExponentialDecay) Rate(base float64, epoch int) float64 {
+	return base * math.Pow(s.gamma, float64(epoch-1))
+}
+
+// CosineAnnealing anneals the learning rate from base to min along a cosine
+// over period epochs, then restarts. Each period is mult times the last
+type CosineAnnealing struct {
+	period int
+	mult   int
+	min    float64
+}
+
+// NewCosineAnnealing returns a CosineAnnealing schedule
+func NewCosineAnnealing(period, mult int, min float64) *CosineAnnealing {
+	return &CosineAnnealing{
+		period: utils.Iparam(period, 10),
+		mult:   utils.Iparam(mult, 1),
+		min:    min,
+	}
+}
+
+// Rate returns the learning rate of epoch
+func (s *CosineAnnealing) Rate(base float64, epoch int) float64 {
+	t, period := epoch-1, s.period
+	for t >= period {
+		t -= period
+		period *= s.mult
+	}
+	return s.min + (base-s.min)*(1+math.Cos(math.Pi*float64(t)/float64(period)))/2
+}
+
+// Warmup raises the learning rate linearly to base over the first epochs,
+// then hands over to another schedule, or keeps base if there is none
+type Warmup struct {
+	epochs int
+	then   Schedule
+}
+
+// NewWarmup returns a Warmup schedule. then sees epochs counted from the
+// end of the warmup
+func NewWarmup(epochs int, then Schedule) *Warmup {
+	return &Warmup{
+		epochs: utils.Iparam(epochs, 5),
+		then:   then,
+	}
+}
+
+// Rate returns the learning rate of epoch
+func (s *Warmup) Rate(base float64, epoch int) float64 {
+	switch {
+	case epoch <= s.epochs:
+		return base * float64(epoch) / float64(s.epochs)
+	case s.then == nil:
+		return base
+	}
+	return s.then.Rate(base, epoch-s.epochs)
+}
+
+// OneCycle raises the learning rate from base/div to base over the first
+// warmup fraction of epochs, then anneals it along a cosine down to
+// base/(div*finalDiv) at the last epoch
+type OneCycle struct {
+	epochs   int
+	warmup   float64
+	div      float64
+	finalDiv float64
+}
+
+// NewOneCycle returns a OneCycle schedule over the given number of epochs
+func NewOneCycle(epochs int, warmup, div, finalDiv float64) *OneCycle {
+	return &OneCycle{
+		epochs:   utils.Iparam(epochs, 100),
+		warmup:   utils.Fparam(warmup, 0.3),
+		div:      utils.Fparam(div, 25),
+		finalDiv: utils.Fparam(finalDiv, 1e4),
+	}
+}
+
+// Rate returns the learning rate of epoch
+func (s *OneCycle) Rate(base float64, epoch int) float64 {
+	start, end := base/s.div, base/(s.div*s.finalDiv)
+	up := math.Max(1, s.warmup*float64(s.epochs))
+	t := float64(epoch - 1)
+	if t < up {
+		return start + (base-start)*t/up
+	}
+	down := math.Max(1, float64(s.epochs-1)-up)
+	p := math.Min(1, (t-up)/down)
+	return end + (base-end)*(1+math.Cos(math.Pi*p))/2
+}
+
+// ReduceOnPlateau multiplies the learning rate by factor whenever the
+// validation loss has not improved by more than minDelta for patience
+// epochs, without going below min
+type ReduceOnPlateau struct {
+	factor   float64
+	patience int
+	minDelta float64
+	min      float64
+
+	scale float64
+	best  float64
+	wait  int
+}
+
+// NewReduceOnPlateau returns a ReduceOnPlateau schedule
+func NewReduceOnPlateau(factor float64, patience int, minDelta, min float64) *ReduceOnPlateau {
+	return &ReduceOnPlateau{
+		factor:   utils.Fparam(factor, 0.1),
+		patience: utils.Iparam(patience, 10),
+		minDelta: minDelta,
+		min:      min,
+		scale:    1,
+		best:     math.Inf(1),
+	}
+}
+
+// Observe records the validation loss at the end of an epoch
+func (s *ReduceOnPlateau) Observe(loss float64) {
+	if loss < s.best-s.minDelta {
+		s.best, s.wait = loss, 0
+		return
+	}
+	s.wait++
+	if s.wait >= s.patience {
+		s.scale *= s.factor
+		s.wait = 0
+	}
+}
+
+// Rate returns the learning rate of epoch
+func (s *ReduceOnPlateau) Rate(base float64, epoch int) float64 {
+	return math.Max(base*s.scale, s.min)
+}
+
+type plateauState struct {
+	Scale float64
+	// Best is omitted until a loss was observed, JSON has no infinity
+	Best *float64 `json:",omitempty"`
+	Wait int
+}
+
+// MarshalJSON encodes the progress of the schedule
+func (s *ReduceOnPlateau) MarshalJSON() ([]byte, error) {
+	state := plateauState{Scale: s.scale, Wait: s.wait}
+	if !math.IsInf(s.best, 1) {
+		state.Best = &s.best
+	}
+	return json.Marshal(state)
+}
+
+// UnmarshalJSON restores the progress of the schedule
+func (s *ReduceOnPlateau) UnmarshalJSON(b []byte) error {
+	var state plateauState
+	if err := json.Unmarshal(b, &state); err != nil {
+		return err
+	}
+	s.scale, s.wait, s.best = state.Scale, state.Wait, math.Inf(1)
+	if state.Best != nil {
+		s.best = *state.Best
+	}
+	return nil
+}
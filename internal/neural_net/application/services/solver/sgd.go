@@ -1,6 +1,9 @@
 package solver
 
-import "main/internal/neural_net/domain/utils"
+import (
+	"encoding/json"
+	"main/internal/neural_net/domain/utils"
+)
 
 // Solver implements an update rule for training a NN
 type Solver interface {
@@ -8,6 +11,14 @@ type Solver interface {
 	Update(value, gradient float64, iteration, idx int) float64
 }
 
+// Stateful is a solver whose state can be saved and restored, for resuming
+// training. Every solver of this package is Stateful
+type Stateful interface {
+	Solver
+	json.Marshaler
+	json.Unmarshaler
+}
+
 // SGD is stochastic gradient descent with nesterov/momentum
 type SGD struct {
 	lr       float64
@@ -44,3 +55,34 @@ func (o *SGD) Update(value, gradient float64, iteration, idx int) float64 {
 
 	return o.moments[idx]
 }
+
+// LearningRate returns the learning rate, before decay
+func (o *SGD) LearningRate() float64 {
+	return o.lr
+}
+
+// SetLearningRate sets the learning rate
+func (o *SGD) SetLearningRate(lr float64) {
+	o.lr = lr
+}
+
+type sgdState struct {
+	LR, Decay, Momentum float64
+	Nesterov            bool
+	Moments             []float64
+}
+
+// MarshalJSON encodes the state of the solver
+func (o *SGD) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sgdState{o.lr, o.decay, o.momentum, o.nesterov, o.moments})
+}
+
+// UnmarshalJSON restores the state of the solver
+func (o *SGD) UnmarshalJSON(b []byte) error {
+	var s sgdState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	o.lr, o.decay, o.momentum, o.nesterov, o.moments = s.LR, s.Decay, s.Momentum, s.Nesterov, s.Moments
+	return nil
+}
@@ -15,6 +15,10 @@ type GetReq struct {
 	VerifyCode  int64  `json:"verify_code,omitempty"`
 }
 
+type PredictReq struct {
+	Input []float64 `json:"input" validate:"required,min=1"`
+}
+
 type HandlerResponse struct {
 	Error   bool        `json:"error"`
 	Message string      `json:"message"`
@@ -40,6 +44,36 @@ const (
 // ActivationType is represents a neuron activation function
 type ActivationType int
 
+func (a ActivationType) String() string {
+	switch a {
+	case ActivationSigmoid:
+		return "Sigmoid"
+	case ActivationTanh:
+		return "Tanh"
+	case ActivationReLU:
+		return "ReLU"
+	case ActivationLinear:
+		return "Linear"
+	case ActivationSoftmax:
+		return "Softmax"
+	case ActivationLeakyReLU:
+		return "LeakyReLU"
+	case ActivationELU:
+		return "ELU"
+	case ActivationSELU:
+		return "SELU"
+	case ActivationGELU:
+		return "GELU"
+	case ActivationSwish:
+		return "Swish"
+	case ActivationSoftplus:
+		return "Softplus"
+	case ActivationHardSigmoid:
+		return "HardSigmoid"
+	}
+	return "N/A"
+}
+
 const (
 	// ActivationNone is no activation
 	ActivationNone ActivationType = 0
@@ -53,6 +87,20 @@ const (
 	ActivationLinear ActivationType = 4
 	// ActivationSoftmax is a softmax activation (per layer)
 	ActivationSoftmax ActivationType = 5
+	// ActivationLeakyReLU is rectified linear unit activation with a negative slope
+	ActivationLeakyReLU ActivationType = 6
+	// ActivationELU is exponential linear unit activation
+	ActivationELU ActivationType = 7
+	// ActivationSELU is scaled exponential linear unit activation
+	ActivationSELU ActivationType = 8
+	// ActivationGELU is gaussian error linear unit activation
+	ActivationGELU ActivationType = 9
+	// ActivationSwish is self-gated (x * sigmoid(x)) activation
+	ActivationSwish ActivationType = 10
+	// ActivationSoftplus is softplus activation
+	ActivationSoftplus ActivationType = 11
+	// ActivationHardSigmoid is piecewise linear sigmoid activation
+	ActivationHardSigmoid ActivationType = 12
 )
 
 // Config defines the network topology, activations, losses etc
@@ -64,16 +112,100 @@ type Config struct {
 	// containing 5 and 3 nodes respectively, followed an output layer
 	// containing 3 nodes.
 	Layout []int
-	// Activation functions: {ActivationTanh, ActivationReLU, ActivationSigmoid}
+	// Activation functions: {ActivationTanh, ActivationReLU, ActivationSigmoid,
+	// ActivationLeakyReLU, ActivationELU, ActivationSELU, ActivationGELU,
+	// ActivationSwish, ActivationSoftplus, ActivationHardSigmoid}
 	Activation ActivationType
+	// Name of an activation registered with utils.RegisterActivation,
+	// overrides Activation
+	ActivationName string `json:",omitempty"`
+	// Parameter of parameterized activations: the negative slope of
+	// ActivationLeakyReLU (default 0.01) and the alpha of ActivationELU
+	// (default 1)
+	ActivationAlpha float64
 	// Solver modes: {ModeRegression, ModeBinary, ModeMultiClass, ModeMultiLabel}
 	Mode Mode
 	// Initializer for weights: {NewNormal(σ, μ), NewUniform(σ, μ)}
 	Weight synapse.WeightInitializer `json:"-"`
-	// Loss functions: {LossCrossEntropy, LossBinaryCrossEntropy, LossMeanSquared}
+	// Seed of the random source of the initializers. NewNeural draws one
+	// from the global source when unset and records it, so that a dump tells
+	// how to rebuild the initial network
+	Seed int64 `json:",omitempty"`
+	// Loss functions: {LossCrossEntropy, LossBinaryCrossEntropy, LossMeanSquared,
+	// LossHuber, LossMeanAbsolute, LossLogCosh, LossQuantile, LossFocal,
+	// LossBinaryFocal}
 	Loss LossType
+	// Class weights of LossCrossEntropy and LossFocal, one per output. For
+	// LossBinaryCrossEntropy and LossBinaryFocal: [negatives, positives]
+	ClassWeights []float64 `json:",omitempty"`
+	// Focusing parameter of LossFocal and LossBinaryFocal (default 2)
+	FocalGamma float64 `json:",omitempty"`
+	// Weight of positives in LossBinaryFocal, negatives get 1-FocalAlpha
+	// (default none)
+	FocalAlpha float64 `json:",omitempty"`
+	// Threshold between the quadratic and linear regime of LossHuber (default 1)
+	HuberDelta float64 `json:",omitempty"`
+	// Quantiles estimated by the outputs under LossQuantile, one per output or
+	// a single one for all outputs (default 0.5)
+	Quantiles []float64 `json:",omitempty"`
+	// Name of a loss registered with services.RegisterLoss, overrides Loss
+	LossName string `json:",omitempty"`
 	// Apply bias nodes
 	Bias bool
+	// L1 and L2 penalties on the weights (both for elastic net), added to the
+	// loss and its gradient. Bias weights are not penalized
+	L1, L2 float64 `json:",omitempty"`
+	// Decoupled weight decay: every training update shrinks the weights by
+	// this fraction, independently of the solver. Bias weights do not decay
+	WeightDecay float64 `json:",omitempty"`
+	// Gradient clipping applied by trainers before every solver update: each
+	// component is clamped to [-ClipValue, ClipValue], then the gradient is
+	// rescaled if its global L2 norm exceeds ClipNorm
+	ClipValue, ClipNorm float64 `json:",omitempty"`
+	// Per-layer settings, indexed like Layout. Unset fields fall back to the
+	// network-wide settings above; NewNeural fills them in so that a dump
+	// records the exact activation and bias of every layer
+	Layers []LayerConfig
+}
+
+// LayerConfig overrides the network-wide settings for a single layer
+type LayerConfig struct {
+	// Activation of the layer. Defaults to Activation for hidden layers and
+	// to the activation implied by Mode for the output layer
+	Activation ActivationType
+	// Name of a registered activation, overrides Activation
+	ActivationName string `json:",omitempty"`
+	// Parameter of the activation, defaults to ActivationAlpha
+	ActivationAlpha float64
+	// Apply bias nodes, defaults to Bias (never for a regression output)
+	Bias *bool `json:",omitempty"`
+	// Initializer for the incoming weights of the layer, defaults to Weight
+	Weight synapse.WeightInitializer `json:"-"`
+	// Fraction of the outputs of a hidden layer dropped during training, in
+	// [0, 1). Kept outputs are scaled up instead of scaling at inference
+	Dropout float64 `json:",omitempty"`
+	// Normalization of the sums of the layer before its activation:
+	// {NormBatch, NormLayer}
+	Norm NormType `json:",omitempty"`
+}
+
+// NormType represents a normalization of the sums of a layer
+type NormType int
+
+const (
+	// NormNone is no normalization
+	NormNone NormType = 0
+	// NormBatch is batch normalization. Training normalizes over the rows of
+	// each batch and tracks running statistics, which are used at inference.
+	// It needs batches of several rows, see BatchTrainer
+	NormBatch NormType = 1
+	// NormLayer is layer normalization, over the neurons of the layer
+	NormLayer NormType = 2
+)
+
+// Bool returns a pointer to v, for use in optional settings
+func Bool(v bool) *bool {
+	return &v
 }
 
 // LossType represents a loss function
@@ -87,6 +219,18 @@ func (l LossType) String() string {
 		return "BinCE"
 	case LossMeanSquared:
 		return "MSE"
+	case LossHuber:
+		return "Huber"
+	case LossMeanAbsolute:
+		return "MAE"
+	case LossLogCosh:
+		return "LogCosh"
+	case LossQuantile:
+		return "Quantile"
+	case LossFocal:
+		return "Focal"
+	case LossBinaryFocal:
+		return "BinFocal"
 	}
 	return "N/A"
 }
@@ -100,4 +244,212 @@ const (
 	LossBinaryCrossEntropy LossType = 2
 	// LossMeanSquared is MSE
 	LossMeanSquared LossType = 3
+	// LossHuber is Huber loss, see Config.HuberDelta
+	LossHuber LossType = 4
+	// LossMeanAbsolute is MAE
+	LossMeanAbsolute LossType = 5
+	// LossLogCosh is log-cosh loss
+	LossLogCosh LossType = 6
+	// LossQuantile is quantile (pinball) loss, see Config.Quantiles
+	LossQuantile LossType = 7
+	// LossFocal is focal loss for softmax outputs, see Config.FocalGamma
+	LossFocal LossType = 8
+	// LossBinaryFocal is focal loss for sigmoid outputs, see Config.FocalGamma
+	// and Config.FocalAlpha
+	LossBinaryFocal LossType = 9
+)
+
+// MetricType represents a measure of the quality of a network on a set of
+// examples. Classification metrics decide the class of an output by its
+// largest value for ModeMultiClass, and every output by the threshold of the
+// network otherwise (default 0.5). Averaged metrics are the mean over classes
+// or labels (macro), or are computed from the counts of all of them (micro)
+type MetricType int
+
+const (
+	// MetricLoss is the loss of the network, penalties included (lower is better)
+	MetricLoss MetricType = 0
+	// MetricAccuracy is the fraction of examples whose decided classes all
+	// match the responses
+	MetricAccuracy MetricType = 1
+	// MetricPrecision is the macro-averaged precision
+	MetricPrecision MetricType = 2
+	// MetricRecall is the macro-averaged recall
+	MetricRecall MetricType = 3
+	// MetricF1 is the macro-averaged F1 score
+	MetricF1 MetricType = 4
+	// MetricMicroPrecision is the micro-averaged precision
+	MetricMicroPrecision MetricType = 5
+	// MetricMicroRecall is the micro-averaged recall
+	MetricMicroRecall MetricType = 6
+	// MetricMicroF1 is the micro-averaged F1 score
+	MetricMicroF1 MetricType = 7
+	// MetricROCAUC is the area under the ROC curve, macro-averaged over
+	// outputs
+	MetricROCAUC MetricType = 8
+	// MetricPRAUC is the area under the precision-recall curve (average
+	// precision), macro-averaged over outputs
+	MetricPRAUC MetricType = 9
+	// MetricLogLoss is the cross-entropy of the outputs as probabilities,
+	// without penalties (lower is better)
+	MetricLogLoss MetricType = 10
+	// MetricMAE is the mean absolute error (lower is better)
+	MetricMAE MetricType = 11
+	// MetricRMSE is the root mean squared error (lower is better)
+	MetricRMSE MetricType = 12
+	// MetricR2 is the coefficient of determination, averaged over outputs
+	MetricR2 MetricType = 13
+	// MetricMAPE is the mean absolute percentage error over nonzero
+	// responses (lower is better)
+	MetricMAPE MetricType = 14
+	// MetricDirectionalAccuracy is the fraction of examples, in time order,
+	// whose output moves from the previous response in the same direction
+	// as their response
+	MetricDirectionalAccuracy MetricType = 15
 )
+
+func (m MetricType) String() string {
+	switch m {
+	case MetricLoss:
+		return "Loss"
+	case MetricAccuracy:
+		return "Accuracy"
+	case MetricPrecision:
+		return "Precision"
+	case MetricRecall:
+		return "Recall"
+	case MetricF1:
+		return "F1"
+	case MetricMicroPrecision:
+		return "Micro-Precision"
+	case MetricMicroRecall:
+		return "Micro-Recall"
+	case MetricMicroF1:
+		return "Micro-F1"
+	case MetricROCAUC:
+		return "ROC-AUC"
+	case MetricPRAUC:
+		return "PR-AUC"
+	case MetricLogLoss:
+		return "LogLoss"
+	case MetricMAE:
+		return "MAE"
+	case MetricRMSE:
+		return "RMSE"
+	case MetricR2:
+		return "R2"
+	case MetricMAPE:
+		return "MAPE"
+	case MetricDirectionalAccuracy:
+		return "DirectionalAccuracy"
+	}
+	return "N/A"
+}
+
+// HigherIsBetter reports whether larger values of m mean a better network
+func (m MetricType) HigherIsBetter() bool {
+	switch m {
+	case MetricLoss, MetricLogLoss, MetricMAE, MetricRMSE, MetricMAPE:
+		return false
+	}
+	return true
+}
+
+// EarlyStopping stops training once the monitored metric of the validation
+// examples has not improved by more than MinDelta for Patience epochs
+type EarlyStopping struct {
+	// Metric monitored, loss by default
+	Monitor MetricType
+	// Epochs without improvement before stopping (default 10)
+	Patience int
+	// Smallest change of the metric counted as an improvement
+	MinDelta float64
+}
+
+// ShufflePolicy tells how trainers order the training examples
+type ShufflePolicy int
+
+const (
+	// ShuffleEpoch shuffles the examples anew every epoch
+	ShuffleEpoch ShufflePolicy = 0
+	// ShuffleOnce shuffles the examples once, every epoch then sees the
+	// same order
+	ShuffleOnce ShufflePolicy = 1
+	// ShuffleNone keeps the examples in the order given
+	ShuffleNone ShufflePolicy = 2
+)
+
+// TrainerConfig configures a trainer. Zero values select the defaults
+type TrainerConfig struct {
+	// Examples per update (default 1, online training)
+	BatchSize int
+	// Workers computing the gradient of a batch, each on a contiguous shard
+	// of it (default 1)
+	Parallelism int
+	// Order of the examples: {ShuffleEpoch, ShuffleOnce, ShuffleNone}
+	Shuffle ShufflePolicy
+	// Epochs between evaluations of the validation examples (default 1).
+	// Early stopping and plateau schedules only see those epochs, and
+	// early stopping patience counts evaluations
+	ValidationFrequency int
+	// Metrics of the validation examples printed with progress (default the
+	// loss, and accuracy for ModeMultiClass)
+	Metrics []MetricType
+	// Epochs between printed progress rows, none when zero
+	Verbosity int
+}
+
+// StopReason tells why training stopped
+type StopReason int
+
+const (
+	// StopCompleted is the end of the requested epochs
+	StopCompleted StopReason = 0
+	// StopEarly is early stopping, the monitored metric stopped improving
+	StopEarly StopReason = 1
+	// StopCallback is a stop requested by a callback
+	StopCallback StopReason = 2
+	// StopCanceled is the cancellation of the context given to Train
+	StopCanceled StopReason = 3
+)
+
+func (r StopReason) String() string {
+	switch r {
+	case StopCompleted:
+		return "completed"
+	case StopEarly:
+		return "early stopping"
+	case StopCallback:
+		return "stopped by callback"
+	case StopCanceled:
+		return "canceled"
+	}
+	return "N/A"
+}
+
+// CalibrationType is a method mapping the output of a ModeBinary network to
+// a calibrated probability
+type CalibrationType int
+
+const (
+	// CalibrationNone keeps the outputs as they are
+	CalibrationNone CalibrationType = 0
+	// CalibrationPlatt fits a sigmoid to the logit of the output (Platt
+	// scaling)
+	CalibrationPlatt CalibrationType = 1
+	// CalibrationIsotonic fits a non-decreasing piecewise linear function to
+	// the output (isotonic regression)
+	CalibrationIsotonic CalibrationType = 2
+)
+
+func (c CalibrationType) String() string {
+	switch c {
+	case CalibrationNone:
+		return "none"
+	case CalibrationPlatt:
+		return "Platt"
+	case CalibrationIsotonic:
+		return "isotonic"
+	}
+	return "N/A"
+}
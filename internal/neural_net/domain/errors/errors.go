@@ -1 +1,20 @@
 package errors
+
+import "errors"
+
+var (
+	// ErrNotTrained is returned when predicting before a model was trained
+	ErrNotTrained = errors.New("neural net is not trained yet")
+	// ErrUnknownActivation is returned for an activation name that was never registered
+	ErrUnknownActivation = errors.New("unknown activation")
+	// ErrUnknownLoss is returned for a loss name that was never registered
+	ErrUnknownLoss = errors.New("unknown loss")
+	// ErrInvalidConfig is returned for a config whose settings do not fit its
+	// layout
+	ErrInvalidConfig = errors.New("invalid config")
+	// ErrDiverged is returned when training drives a weight to NaN or Inf
+	ErrDiverged = errors.New("training diverged")
+	// ErrUnsupportedMode is returned for an operation that does not apply to
+	// the mode of the network
+	ErrUnsupportedMode = errors.New("unsupported mode")
+)
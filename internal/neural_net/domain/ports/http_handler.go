@@ -1,5 +1,10 @@
 package ports
 
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
 // IHandlers Auth Domain HTTP handler interface
 type IHandlers interface {
+	Predict(c *fiber.Ctx) error
 }
@@ -1,10 +1,11 @@
 package ports
 
-// Differentiable is an activation function and its first order derivative,
-// where the latter is expressed as a function of the former for efficiency
+// Differentiable is an activation function and its first order derivative.
+// The derivative receives both the pre-activation x and the output y = F(x),
+// so that it can be expressed in terms of whichever is cheaper
 type Differentiable interface {
-	F(float64) float64
-	Df(float64) float64
+	F(x float64) float64
+	Df(x, y float64) float64
 }
 
 /*
@@ -1,6 +1,9 @@
 package ports
 
+import "context"
+
 // IService Auth domain service interface
 type IService interface {
-	Train()
+	Train(ctx context.Context) error
+	Predict([]float64) ([]float64, error)
 }
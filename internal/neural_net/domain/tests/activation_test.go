@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/domain/entities"
+	"main/internal/neural_net/domain/utils"
+	"testing"
+)
+
+var activations = []entities.ActivationType{
+	entities.ActivationSigmoid,
+	entities.ActivationTanh,
+	entities.ActivationReLU,
+	entities.ActivationLinear,
+	entities.ActivationLeakyReLU,
+	entities.ActivationELU,
+	entities.ActivationSELU,
+	entities.ActivationGELU,
+	entities.ActivationSwish,
+	entities.ActivationSoftplus,
+	entities.ActivationHardSigmoid,
+}
+
+func Test_ActivationDerivatives(t *testing.T) {
+	const h = 1e-6
+	for _, act := range activations {
+		a := utils.GetActivation(act, 0)
+		for _, x := range []float64{-4, -2.5, -1, -0.3, 0.2, 0.7, 1.5, 3.5} {
+			numeric := (a.F(x+h) - a.F(x-h)) / (2 * h)
+			assert.InDelta(t, numeric, a.Df(x, a.F(x)), 1e-6, "activation %d at %f", act, x)
+		}
+	}
+}
+
+func Test_ActivationAlpha(t *testing.T) {
+	leaky := utils.GetActivation(entities.ActivationLeakyReLU, 0.2)
+	assert.Equal(t, -0.4, leaky.F(-2))
+	assert.Equal(t, 0.2, leaky.Df(-2, leaky.F(-2)))
+	assert.Equal(t, -0.02, utils.GetActivation(entities.ActivationLeakyReLU, 0).F(-2))
+
+	elu := utils.GetActivation(entities.ActivationELU, 2)
+	assert.InDelta(t, -2, elu.F(-50), 1e-9)
+	assert.Equal(t, 3.0, elu.F(3))
+}
+
+func Test_Softplus(t *testing.T) {
+	a := utils.GetActivation(entities.ActivationSoftplus, 0)
+	assert.Equal(t, 1000.0, a.F(1000))
+	assert.InDelta(t, 0, a.F(-1000), 1e-12)
+}
@@ -1,6 +1,9 @@
 package tests
 
 import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
 	"main/internal/neural_net/application/services"
 	"main/internal/neural_net/application/services/layer/neuron/synapse"
 	"main/internal/neural_net/application/services/solver"
@@ -11,16 +14,18 @@ import (
 	"time"
 )
 
+// Benchmark_xor measures batch training with 1, 2, 4... workers up to
+// runtime.NumCPU(), the time per run should drop near-linearly
 func Benchmark_xor(b *testing.B) {
 	rand.Seed(time.Now().Unix())
-	n := services.NewNeural(&entities.Config{
+	config := &entities.Config{
 		Inputs:     2,
 		Layout:     []int{3, 3, 1},
 		Activation: entities.ActivationSigmoid,
 		Mode:       entities.ModeBinary,
 		Weight:     synapse.NewUniform(.25, 0),
 		Bias:       true,
-	})
+	}
 	exs := services.Examples{
 		{[]float64{0, 0}, []float64{0}},
 		{[]float64{1, 0}, []float64{1}},
@@ -33,10 +38,90 @@ func Benchmark_xor(b *testing.B) {
 		dupExs = append(dupExs, exs...)
 	}
 
+	workers := []int{1}
+	for w := 2; w < runtime.NumCPU(); w *= 2 {
+		workers = append(workers, w)
+	}
+	if runtime.NumCPU() > 1 {
+		workers = append(workers, runtime.NumCPU())
+	}
+	for _, w := range workers {
+		b.Run(fmt.Sprintf("workers=%d", w), func(b *testing.B) {
+			n := services.NewNeural(config)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				const iterations = 20
+				solver := solver.NewAdam(0.001, 0.9, 0.999, 1e-100)
+				trainer := services.NewBatchTrainer(solver, 0, len(dupExs)/2, w)
+				trainer.Train(context.Background(), n, dupExs, nil, iterations)
+			}
+		})
+	}
+}
+
+func Benchmark_online_xor(b *testing.B) {
+	rand.Seed(0)
+	n := services.NewNeural(&entities.Config{
+		Inputs:     2,
+		Layout:     []int{16, 16, 1},
+		Activation: entities.ActivationSigmoid,
+		Mode:       entities.ModeBinary,
+		Weight:     synapse.NewUniform(.25, 0),
+		Bias:       true,
+	})
+	exs := services.Examples{
+		{[]float64{0, 0}, []float64{0}},
+		{[]float64{1, 0}, []float64{1}},
+		{[]float64{0, 1}, []float64{1}},
+		{[]float64{1, 1}, []float64{0}},
+	}
+	var dupExs services.Examples
+	for len(dupExs) < 1000 {
+		dupExs = append(dupExs, exs...)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		trainer := services.NewTrainer(solver.NewSGD(0.1, 0.1, 0, false), 0)
+		trainer.Train(context.Background(), n, dupExs, nil, 5)
+	}
+}
+
+func Benchmark_predict(b *testing.B) {
+	rand.Seed(0)
+	n := services.NewNeural(&entities.Config{
+		Inputs:     32,
+		Layout:     []int{64, 64, 8},
+		Activation: entities.ActivationReLU,
+		Mode:       entities.ModeMultiClass,
+		Weight:     synapse.NewNormal(0.1, 0),
+		Bias:       true,
+	})
+	input := make([]float64, 32)
+	for i := range input {
+		input[i] = rand.Float64()
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		const iterations = 20
-		solver := solver.NewAdam(0.001, 0.9, 0.999, 1e-100)
-		trainer := services.NewBatchTrainer(solver, iterations, len(dupExs)/2, runtime.NumCPU())
-		trainer.Train(n, dupExs, dupExs, iterations)
+		n.Predict(input)
+	}
+}
+
+func Test_BatchTrainerAllocations(t *testing.T) {
+	allocs := func(examples int) float64 {
+		var exs services.Examples
+		for len(exs) < examples {
+			exs = append(exs, xor...)
+		}
+		n := services.NewNeural(normConfig(entities.NormBatch))
+		return testing.AllocsPerRun(3, func() {
+			trainer := services.NewBatchTrainer(solver.NewAdam(0.01, 0, 0, 0), 0, 4, 2)
+			trainer.Train(context.Background(), n, exs, nil, 2)
+		})
 	}
+	// Batches allocate nothing, however many there are: 512 batches more
+	// than the 8 of 16 examples cost at most an allocation of noise
+	assert.InDelta(t, allocs(16), allocs(1024), 1)
 }
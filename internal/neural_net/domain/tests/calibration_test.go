@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/metrics"
+	"main/internal/neural_net/domain/entities"
+	nnErrors "main/internal/neural_net/domain/errors"
+	"testing"
+)
+
+// sigmoidNet is a ModeBinary network whose output is sigmoid(x)
+func sigmoidNet() *services.Neural {
+	n := services.NewNeural(&entities.Config{
+		Inputs: 1,
+		Layout: []int{1},
+		Mode:   entities.ModeBinary,
+		Bias:   true,
+	})
+	n.ApplyWeights([][][]float64{{{1, 0}}})
+	return n
+}
+
+// steps are examples of sigmoidNet whose responses are not monotonic in the
+// output
+var steps = services.Examples{
+	{Input: []float64{-2}, Response: []float64{0}},
+	{Input: []float64{-1}, Response: []float64{1}},
+	{Input: []float64{0}, Response: []float64{0}},
+	{Input: []float64{1}, Response: []float64{1}},
+	{Input: []float64{2}, Response: []float64{1}},
+}
+
+func Test_Thresholds(t *testing.T) {
+	estimate := [][]float64{{.9, .2}, {.7, .4}, {.4, .3}, {.3, .6}, {.1, .1}}
+	ideal := [][]float64{{1, 0}, {0, 0}, {1, 0}, {0, 0}, {0, 0}}
+
+	// F1 of the first label is best deciding .9 and .4 positive, the second
+	// label has no positives
+	assert.Equal(t, []float64{0.35, 0.5}, metrics.Thresholds(estimate, ideal))
+	d := metrics.Decision{Mode: entities.ModeMultiLabel, Thresholds: []float64{0.35, 0.5}}
+	assert.Equal(t, []bool{true, false}, d.Decide([]float64{0.4, 0.4}))
+	// ModeMultiClass decides the largest output whatever the thresholds
+	d.Mode = entities.ModeMultiClass
+	assert.Equal(t, []bool{false, true}, d.Decide([]float64{.4, .6}))
+}
+
+func Test_FitThresholds(t *testing.T) {
+	n := services.NewNeural(&entities.Config{
+		Inputs:     2,
+		Layout:     []int{4, 2},
+		Activation: entities.ActivationTanh,
+		Mode:       entities.ModeMultiLabel,
+		Bias:       true,
+		Seed:       1,
+	})
+	labels := services.Examples{
+		{Input: []float64{0, 0}, Response: []float64{0, 1}},
+		{Input: []float64{0, 1}, Response: []float64{1, 0}},
+		{Input: []float64{1, 0}, Response: []float64{1, 1}},
+		{Input: []float64{1, 1}, Response: []float64{0, 1}},
+	}
+	before := services.Evaluate(n, labels, entities.MetricF1)
+	assert.Nil(t, n.FitThresholds(labels))
+	assert.Len(t, n.Thresholds, 2)
+	assert.GreaterOrEqual(t, services.Evaluate(n, labels, entities.MetricF1), before)
+	assert.Equal(t, n.Decision().Decide(n.Predict(labels[0].Input)), n.Classify(labels[0].Input))
+
+	restored, err := services.Unmarshal(mustMarshal(t, n))
+	assert.Nil(t, err)
+	assert.Equal(t, n.Thresholds, restored.Thresholds)
+	assert.Equal(t, n.Decision(), services.NewPredictor(restored).Decision())
+
+	regression := services.NewNeural(&entities.Config{Inputs: 1, Layout: []int{1}, Mode: entities.ModeRegression})
+	assert.ErrorIs(t, regression.FitThresholds(steps), nnErrors.ErrUnsupportedMode)
+	assert.ErrorIs(t, regression.Calibrate(steps, entities.CalibrationPlatt), nnErrors.ErrUnsupportedMode)
+}
+
+func Test_IsotonicCalibration(t *testing.T) {
+	n := sigmoidNet()
+	assert.Nil(t, n.Calibrate(steps, entities.CalibrationIsotonic))
+
+	// The second and third examples are pooled
+	var calibrated []float64
+	for _, e := range steps {
+		calibrated = append(calibrated, n.Predict(e.Input)[0])
+	}
+	assert.Equal(t, []float64{0, 0.5, 0.5, 1, 1}, calibrated)
+	// Outputs between examples are interpolated, beyond them clamped
+	assert.InDelta(t, 0.75, n.Calibration.Apply((n.Calibration.X[2]+n.Calibration.X[3])/2), 1e-12)
+	assert.Equal(t, 0.0, n.Predict([]float64{-5})[0])
+
+	restored, err := services.Unmarshal(mustMarshal(t, n))
+	assert.Nil(t, err)
+	out := make([]float64, 1)
+	assert.Nil(t, services.NewPredictor(restored).Predict([]float64{0.5}, out))
+	assert.Equal(t, n.Predict([]float64{0.5}), out)
+
+	assert.Nil(t, n.Calibrate(nil, entities.CalibrationNone))
+	assert.Nil(t, n.Calibration)
+	assert.InDelta(t, 0.5, n.Predict([]float64{0})[0], 1e-12)
+}
+
+func Test_PlattCalibration(t *testing.T) {
+	// sigmoidNet is overconfident: a fifth of the examples on either side
+	// of 0 are misclassified
+	var noisy services.Examples
+	for _, x := range []float64{-4, -3, 3, 4} {
+		for i := 0; i < 5; i++ {
+			y := 0.0
+			if (x > 0) != (i == 0) {
+				y = 1
+			}
+			noisy = append(noisy, services.Example{Input: []float64{x}, Response: []float64{y}})
+		}
+	}
+	n := sigmoidNet()
+	raw := services.Evaluate(n, noisy, entities.MetricLogLoss)
+	assert.Nil(t, n.Calibrate(noisy, entities.CalibrationPlatt))
+	assert.Equal(t, entities.CalibrationPlatt, n.Calibration.Method)
+	assert.Less(t, services.Evaluate(n, noisy, entities.MetricLogLoss), raw)
+	// Much closer to the rate of positives, symmetric around 0
+	p := n.Predict([]float64{3})[0]
+	assert.True(t, p > 0.65 && p < 0.85, p)
+	assert.InDelta(t, 1-p, n.Predict([]float64{-3})[0], 1e-6)
+}
+
+func mustMarshal(t *testing.T, n *services.Neural) []byte {
+	b, err := n.Marshal()
+	assert.Nil(t, err)
+	return b
+}
@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/solver"
+	"main/internal/neural_net/domain/entities"
+	"main/pkg/logger"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+type recorder struct {
+	events []string
+	losses []float64
+}
+
+func (r *recorder) callback() services.Callback {
+	return services.CallbackFuncs{
+		EpochStart: func(p *services.Progress) error {
+			r.events = append(r.events, fmt.Sprintf("start %d", p.Epoch))
+			return nil
+		},
+		BatchEnd: func(p *services.Progress) error {
+			r.events = append(r.events, fmt.Sprintf("batch %d.%d", p.Epoch, p.Batch))
+			r.losses = append(r.losses, p.Loss)
+			return nil
+		},
+		EpochEnd: func(p *services.Progress) error {
+			r.events = append(r.events, fmt.Sprintf("end %d", p.Epoch))
+			r.losses = append(r.losses, p.Loss)
+			return nil
+		},
+		TrainEnd: func(p *services.Progress) error {
+			r.events = append(r.events, fmt.Sprintf("done %d %s", p.Result.Epochs, p.Result.Stop))
+			return nil
+		},
+	}
+}
+
+func Test_CallbacksOnline(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(normConfig(entities.NormNone))
+	r := &recorder{}
+	_, err := services.NewTrainer(solver.NewSGD(0.1, 0, 0, false), 0).
+		WithCallbacks(r.callback()).
+		Train(context.Background(), n, xor[:2], nil, 2)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{
+		"start 1", "batch 1.0", "batch 1.1", "end 1",
+		"start 2", "batch 2.0", "batch 2.1", "end 2",
+		"done 2 completed",
+	}, r.events)
+	assert.InDelta(t, (r.losses[0]+r.losses[1])/2, r.losses[2], 1e-12)
+	for _, l := range r.losses {
+		assert.Greater(t, l, 0.0)
+	}
+}
+
+func Test_CallbacksBatch(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(normConfig(entities.NormNone))
+	r := &recorder{}
+	_, err := services.NewBatchTrainer(solver.NewSGD(0.1, 0, 0, false), 0, 2, 2).
+		WithCallbacks(r.callback()).
+		Train(context.Background(), n, xor, nil, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"start 1", "batch 1.0", "batch 1.1", "end 1", "done 1 completed"}, r.events)
+
+	// The loss of a batch is that of its examples before the update
+	before := services.FromDump(n.Dump())
+	r.losses = nil
+	_, err = services.NewBatchTrainer(solver.NewSGD(0.1, 0, 0, false), 0, 4, 2).
+		WithCallbacks(r.callback()).
+		Train(context.Background(), n, xor, nil, 1)
+	assert.Nil(t, err)
+	assert.InDelta(t, services.CrossValidate(before, xor), r.losses[0], 1e-12)
+}
+
+func Test_CallbackMetrics(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(normConfig(entities.NormNone))
+	var checked int
+	cb := services.CallbackFuncs{
+		EpochEnd: func(p *services.Progress) error {
+			assert.Equal(t, services.CrossValidate(p.Network, xor), p.Metric(entities.MetricLoss))
+			assert.Equal(t, services.Accuracy(p.Network, xor), p.Metric(entities.MetricAccuracy))
+			checked++
+			return nil
+		},
+	}
+	_, err := services.NewTrainer(solver.NewSGD(0.1, 0, 0, false), 0).WithCallbacks(cb).Train(context.Background(), n, xor, xor, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, checked)
+
+	// Without validation examples
+	cb.EpochEnd = func(p *services.Progress) error {
+		assert.True(t, math.IsNaN(p.Metric(entities.MetricLoss)))
+		checked++
+		return nil
+	}
+	_, err = services.NewTrainer(solver.NewSGD(0.1, 0, 0, false), 0).WithCallbacks(cb).Train(context.Background(), n, xor, nil, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, checked)
+}
+
+func Test_CallbackStop(t *testing.T) {
+	for _, stopOnBatch := range []bool{false, true} {
+		rand.Seed(0)
+		n := services.NewNeural(normConfig(entities.NormNone))
+		r := &recorder{}
+		stop := func(p *services.Progress) error {
+			if p.Epoch == 3 {
+				p.Stop()
+			}
+			return nil
+		}
+		cb := services.CallbackFuncs{EpochEnd: stop}
+		if stopOnBatch {
+			cb = services.CallbackFuncs{BatchEnd: stop}
+		}
+
+		result, err := services.NewBatchTrainer(solver.NewSGD(0.1, 0, 0, false), 0, 2, 1).
+			WithCallbacks(r.callback(), cb).
+			Train(context.Background(), n, xor, nil, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, entities.StopCallback, result.Stop)
+		assert.Equal(t, 3, result.Epochs)
+		if stopOnBatch {
+			assert.Equal(t, []string{"batch 3.0", "end 3", "done 3 stopped by callback"}, r.events[len(r.events)-3:])
+		}
+	}
+}
+
+func Test_CallbackError(t *testing.T) {
+	n := services.NewNeural(normConfig(entities.NormNone))
+	failure := errors.New("disk full")
+	cb := services.CallbackFuncs{
+		EpochEnd: func(p *services.Progress) error {
+			if p.Epoch == 2 {
+				return failure
+			}
+			return nil
+		},
+	}
+	result, err := services.NewTrainer(solver.NewSGD(0.1, 0, 0, false), 0).WithCallbacks(cb).Train(context.Background(), n, xor, nil, 10)
+	assert.Equal(t, failure, err)
+	assert.Equal(t, 2, result.Epochs)
+}
+
+type testLogger struct {
+	logger.Logger
+	lines []string
+}
+
+func (l *testLogger) Info(args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprint(args...))
+}
+
+func (l *testLogger) Infof(template string, args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(template, args...))
+}
+
+func Test_LoggingCallback(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(normConfig(entities.NormNone))
+	l := &testLogger{}
+	cb := services.NewLoggingCallback(l, 2, entities.MetricLoss, entities.MetricAccuracy)
+	_, err := services.NewTrainer(solver.NewSGD(0.1, 0, 0, false), 0).WithCallbacks(cb).Train(context.Background(), n, xor, xor, 4)
+	assert.Nil(t, err)
+
+	assert.Len(t, l.lines, 3)
+	assert.Regexp(t, `^epoch 2 \(.*\): loss \d\.\d{4}, validation Loss \d\.\d{4}, validation Accuracy \d\.\d{4}, lr 0\.1$`, l.lines[0])
+	assert.Regexp(t, `^epoch 4 `, l.lines[1])
+	assert.Equal(t, "training ended after 4 epochs: completed", l.lines[2])
+}
+
+func Test_CheckpointCallback(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(normConfig(entities.NormNone))
+	var saved []*services.Checkpoint
+	trainer := services.NewTrainer(solver.NewAdam(0.01, 0, 0, 0), 0)
+	trainer.WithCallbacks(services.NewCheckpointCallback(trainer, 3, func(cp *services.Checkpoint) error {
+		saved = append(saved, cp)
+		return nil
+	}))
+	_, err := trainer.Train(context.Background(), n, xor, nil, 7)
+	assert.Nil(t, err)
+
+	assert.Len(t, saved, 3)
+	assert.Equal(t, 3, saved[0].Epoch)
+	assert.Equal(t, 6, saved[1].Epoch)
+	assert.Equal(t, 7, saved[2].Epoch)
+	assert.Equal(t, n.Weights(), saved[2].Dump.Weights)
+}
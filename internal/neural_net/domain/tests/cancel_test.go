@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/solver"
+	"main/internal/neural_net/domain/entities"
+	"math/rand"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func Test_TrainCanceled(t *testing.T) {
+	tests := map[string]struct {
+		newTrainer func(cb services.Callback) services.Trainer
+		batches    int
+	}{
+		"online": {func(cb services.Callback) services.Trainer {
+			return services.NewTrainer(solver.NewSGD(0.1, 0, 0, false), 0).WithCallbacks(cb)
+		}, 2*len(xor) + 1},
+		"batch": {func(cb services.Callback) services.Trainer {
+			return services.NewBatchTrainer(solver.NewSGD(0.1, 0, 0, false), 0, 2, 2).WithCallbacks(cb)
+		}, 2*2 + 1},
+	}
+	for name, tt := range tests {
+		rand.Seed(0)
+		n := services.NewNeural(normConfig(entities.NormNone))
+		ctx, cancel := context.WithCancel(context.Background())
+		var batches int
+		cb := services.CallbackFuncs{
+			BatchEnd: func(p *services.Progress) error {
+				batches++
+				if p.Epoch == 3 {
+					cancel()
+				}
+				return nil
+			},
+		}
+
+		result, err := tt.newTrainer(cb).Train(ctx, n, xor, nil, 10)
+		assert.Equal(t, context.Canceled, err, name)
+		assert.Equal(t, entities.StopCanceled, result.Stop, name)
+		assert.Equal(t, 2, result.Epochs, name)
+		// Training stops right after the batch during which ctx was canceled
+		assert.Equal(t, tt.batches, batches, name)
+	}
+}
+
+func Test_TrainAlreadyCanceled(t *testing.T) {
+	n := services.NewNeural(normConfig(entities.NormNone))
+	before := n.Weights()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := services.NewBatchTrainer(solver.NewSGD(0.1, 0, 0, false), 0, 2, 2).Train(ctx, n, xor, nil, 10)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, &services.Result{Stop: entities.StopCanceled}, result)
+	assert.Equal(t, before, n.Weights())
+}
+
+func Test_BatchTrainerWorkersExit(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 5; i++ {
+		n := services.NewNeural(normConfig(entities.NormNone))
+		_, err := services.NewBatchTrainer(solver.NewSGD(0.1, 0, 0, false), 0, 4, 4).Train(context.Background(), n, xor, nil, 2)
+		assert.Nil(t, err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/layer/neuron/synapse"
+	"main/internal/neural_net/application/services/solver"
+	"main/internal/neural_net/domain/entities"
+	"math/rand"
+	"testing"
+)
+
+type resumable interface {
+	services.Trainer
+	Checkpoint(n *services.Neural) (*services.Checkpoint, error)
+	Resume(cp *services.Checkpoint) (*services.Neural, error)
+}
+
+func checkpointConfig() *entities.Config {
+	return &entities.Config{
+		Inputs:     2,
+		Layout:     []int{6, 1},
+		Activation: entities.ActivationTanh,
+		Mode:       entities.ModeBinary,
+		Weight:     synapse.NewUniform(1, 0),
+		Bias:       true,
+		Layers:     []entities.LayerConfig{{Dropout: 0.2}},
+	}
+}
+
+func Test_CheckpointResume(t *testing.T) {
+	trainers := map[string]func(s solver.Solver) resumable{
+		"online": func(s solver.Solver) resumable { return services.NewTrainer(s, 0) },
+		"batch":  func(s solver.Solver) resumable { return services.NewBatchTrainer(s, 0, 2, 2) },
+	}
+	solvers := map[string]func() solver.Solver{
+		"SGD":  func() solver.Solver { return solver.NewSGD(0.1, 0.9, 0.01, true) },
+		"Adam": func() solver.Solver { return solver.NewAdam(0.01, 0, 0, 0) },
+		"Scheduled": func() solver.Solver {
+			return solver.NewScheduled(solver.NewAMSGrad(0.01, 0, 0, 0), solver.NewReduceOnPlateau(0.5, 2, 0, 0))
+		},
+	}
+
+	for tname, newTrainer := range trainers {
+		for sname, newSolver := range solvers {
+			rand.Seed(0)
+			full := services.NewNeural(checkpointConfig())
+			_, err := newTrainer(newSolver()).Train(context.Background(), full, xor, xor, 30)
+			assert.Nil(t, err)
+
+			rand.Seed(0)
+			n := services.NewNeural(checkpointConfig())
+			trainer := newTrainer(newSolver())
+			_, err = trainer.Train(context.Background(), n, xor, xor, 12)
+			assert.Nil(t, err)
+			cp, err := trainer.Checkpoint(n)
+			assert.Nil(t, err)
+			assert.Equal(t, 12, cp.Epoch)
+
+			b, err := json.Marshal(cp)
+			assert.Nil(t, err)
+			var restored services.Checkpoint
+			assert.Nil(t, json.Unmarshal(b, &restored))
+
+			// A fresh process: new solver, new trainer, unrelated global seed
+			rand.Seed(42)
+			resumed := newTrainer(newSolver())
+			n, err = resumed.Resume(&restored)
+			assert.Nil(t, err)
+			_, err = resumed.Train(context.Background(), n, xor, xor, 30)
+			assert.Nil(t, err)
+
+			assert.Equal(t, full.Weights(), n.Weights(), tname+" "+sname)
+		}
+	}
+}
+
+type memorylessSolver struct{}
+
+func (memorylessSolver) Init(size int) {}
+
+func (memorylessSolver) Update(value, gradient float64, iteration, idx int) float64 {
+	return -0.1 * gradient
+}
+
+func Test_CheckpointStatelessSolver(t *testing.T) {
+	n := services.NewNeural(checkpointConfig())
+	trainer := services.NewTrainer(memorylessSolver{}, 0)
+	_, err := trainer.Train(context.Background(), n, xor, nil, 1)
+	assert.Nil(t, err)
+	_, err = trainer.Checkpoint(n)
+	assert.NotNil(t, err)
+}
+
+func Test_SolverState(t *testing.T) {
+	a := solver.NewAdam(0.01, 0, 0, 0)
+	a.Init(3)
+	a.Update(1, 0.5, 1, 1)
+	b, err := json.Marshal(a)
+	assert.Nil(t, err)
+
+	restored := solver.NewAdam(0, 0, 0, 0)
+	assert.Nil(t, json.Unmarshal(b, restored))
+	assert.Equal(t, a, restored)
+	assert.Equal(t, a.Update(1, 0.2, 2, 1), restored.Update(1, 0.2, 2, 1))
+}
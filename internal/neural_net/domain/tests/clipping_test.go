@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/layer/neuron/synapse"
+	"main/internal/neural_net/application/services/solver"
+	"main/internal/neural_net/domain/entities"
+	nnErrors "main/internal/neural_net/domain/errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func explodingConfig(clipValue, clipNorm float64) *entities.Config {
+	return &entities.Config{
+		Inputs:     2,
+		Layout:     []int{4, 1},
+		Activation: entities.ActivationLinear,
+		Mode:       entities.ModeRegression,
+		Weight:     synapse.NewUniform(1, 0),
+		Bias:       true,
+		ClipValue:  clipValue,
+		ClipNorm:   clipNorm,
+	}
+}
+
+var volatile = services.Examples{
+	{Input: []float64{40, -35}, Response: []float64{1e3}},
+	{Input: []float64{-50, 20}, Response: []float64{-2e3}},
+}
+
+func paramDelta(before, after *services.Neural) []float64 {
+	var d []float64
+	for i, l := range after.Layers {
+		for k, v := range l.Params() {
+			d = append(d, v-before.Layers[i].Params()[k])
+		}
+	}
+	return d
+}
+
+func Test_Diverged(t *testing.T) {
+	for _, trainer := range []services.Trainer{
+		services.NewTrainer(solver.NewSGD(1, 0, 0, false), 0),
+		services.NewBatchTrainer(solver.NewSGD(1, 0, 0, false), 0, 2, 2),
+	} {
+		rand.Seed(0)
+		n := services.NewNeural(explodingConfig(0, 0))
+		_, err := trainer.Train(context.Background(), n, volatile, nil, 100)
+		assert.True(t, errors.Is(err, nnErrors.ErrDiverged), err)
+	}
+}
+
+func Test_ClipNormStable(t *testing.T) {
+	for _, trainer := range []services.Trainer{
+		services.NewTrainer(solver.NewSGD(0.01, 0, 0, false), 0),
+		services.NewBatchTrainer(solver.NewSGD(0.01, 0, 0, false), 0, 2, 2),
+	} {
+		rand.Seed(0)
+		n := services.NewNeural(explodingConfig(0, 1))
+		_, err := trainer.Train(context.Background(), n, volatile, nil, 100)
+		assert.Nil(t, err)
+		for _, l := range n.Layers {
+			for _, w := range l.W {
+				assert.False(t, math.IsNaN(w) || math.IsInf(w, 0))
+			}
+		}
+	}
+}
+
+func Test_ClipValue(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(explodingConfig(0.5, 0))
+	before := services.FromDump(n.Dump())
+	_, err := services.NewTrainer(solver.NewSGD(1, 0, 0, false), 0).Train(context.Background(), n, volatile[:1], nil, 1)
+	assert.Nil(t, err)
+
+	var clipped bool
+	for _, d := range paramDelta(before, n) {
+		assert.LessOrEqual(t, math.Abs(d), 0.5+1e-12)
+		clipped = clipped || math.Abs(d) > 0.5-1e-12
+	}
+	assert.True(t, clipped)
+}
+
+func Test_ClipNorm(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(explodingConfig(0, 2))
+	before := services.FromDump(n.Dump())
+	_, err := services.NewBatchTrainer(solver.NewSGD(1, 0, 0, false), 0, 2, 1).Train(context.Background(), n, volatile, nil, 1)
+	assert.Nil(t, err)
+
+	var norm float64
+	for _, d := range paramDelta(before, n) {
+		norm += d * d
+	}
+	assert.InDelta(t, 2, math.Sqrt(norm), 1e-9)
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/solver"
+	"main/internal/neural_net/domain/entities"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// sequence returns n examples whose input is their index
+func sequence(n int) services.Examples {
+	exs := make(services.Examples, n)
+	for i := range exs {
+		exs[i] = services.Example{Input: []float64{float64(i)}, Response: []float64{float64(i % 2)}}
+	}
+	return exs
+}
+
+func indices(exs services.Examples) []int {
+	res := make([]int, len(exs))
+	for i, e := range exs {
+		res[i] = int(e.Input[0])
+	}
+	return res
+}
+
+func Test_KFold(t *testing.T) {
+	folds, err := sequence(10).KFold(3, nil)
+	assert.Nil(t, err)
+	assert.Len(t, folds, 3)
+	assert.Equal(t, []int{0, 1, 2, 3}, indices(folds[0].Validation))
+	assert.Equal(t, []int{4, 5, 6}, indices(folds[1].Validation))
+	assert.Equal(t, []int{7, 8, 9}, indices(folds[2].Validation))
+	assert.Equal(t, []int{0, 1, 2, 3, 7, 8, 9}, indices(folds[1].Train))
+
+	// Shuffled, every example is validated exactly once
+	folds, err = sequence(10).KFold(3, rand.New(rand.NewSource(1)))
+	assert.Nil(t, err)
+	var validated []int
+	for _, f := range folds {
+		assert.Equal(t, 10, len(f.Train)+len(f.Validation))
+		assert.InDelta(t, 10.0/3, len(f.Validation), 1)
+		validated = append(validated, indices(f.Validation)...)
+	}
+	assert.ElementsMatch(t, indices(sequence(10)), validated)
+	assert.NotEqual(t, []int{0, 1, 2, 3}, indices(folds[0].Validation))
+
+	_, err = sequence(10).KFold(1, nil)
+	assert.NotNil(t, err)
+	_, err = sequence(2).KFold(3, nil)
+	assert.NotNil(t, err)
+}
+
+func Test_StratifiedKFold(t *testing.T) {
+	// 3 positives out of 12 examples
+	exs := make(services.Examples, 12)
+	for i := range exs {
+		exs[i] = services.Example{Input: []float64{float64(i)}, Response: []float64{0}}
+		if i%4 == 0 {
+			exs[i].Response[0] = 1
+		}
+	}
+	folds, err := exs.StratifiedKFold(3, rand.New(rand.NewSource(1)))
+	assert.Nil(t, err)
+	for _, f := range folds {
+		assert.Len(t, f.Validation, 4)
+		var positives int
+		for _, e := range f.Validation {
+			positives += int(e.Response[0])
+		}
+		assert.Equal(t, 1, positives)
+	}
+
+	// One-hot responses
+	onehot := services.Examples{}
+	for i := 0; i < 9; i++ {
+		response := make([]float64, 3)
+		response[i/3] = 1
+		onehot = append(onehot, services.Example{Input: []float64{float64(i)}, Response: response})
+	}
+	folds, err = onehot.StratifiedKFold(3, nil)
+	assert.Nil(t, err)
+	for _, f := range folds {
+		assert.Len(t, f.Validation, 3)
+		assert.Equal(t, []float64{1, 1, 1}, []float64{
+			f.Validation[0].Response[0], f.Validation[1].Response[1], f.Validation[2].Response[2],
+		})
+	}
+}
+
+func Test_TimeSeriesSplit(t *testing.T) {
+	folds, err := sequence(11).TimeSeriesSplit(3, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, indices(folds[0].Train))
+	assert.Equal(t, []int{5, 6}, indices(folds[0].Validation))
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8}, indices(folds[2].Train))
+	assert.Equal(t, []int{9, 10}, indices(folds[2].Validation))
+
+	// Walk-forward
+	folds, err = sequence(11).TimeSeriesSplit(3, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2, 3, 4}, indices(folds[0].Train))
+	assert.Equal(t, []int{4, 5, 6}, indices(folds[1].Train))
+	assert.Equal(t, []int{7, 8}, indices(folds[1].Validation))
+
+	_, err = sequence(3).TimeSeriesSplit(3, 0)
+	assert.NotNil(t, err)
+	for _, f := range []int{0, -1, -2} {
+		_, err = sequence(11).TimeSeriesSplit(f, 0)
+		assert.NotNil(t, err)
+	}
+}
+
+func Test_CrossValidateFolds(t *testing.T) {
+	rand.Seed(0)
+	exs := services.Examples{}
+	for i := 0; i < 40; i++ {
+		exs = append(exs, xor[i%4])
+	}
+	folds, err := exs.StratifiedKFold(4, rand.New(rand.NewSource(1)))
+	assert.Nil(t, err)
+
+	config := normConfig(entities.NormNone)
+	newTrainer := func() services.Trainer {
+		return services.NewTrainer(solver.NewAdam(0.05, 0, 0, 0), 0)
+	}
+	cv, err := services.CrossValidateFolds(context.Background(), config, newTrainer, folds, 100)
+	assert.Nil(t, err)
+
+	assert.Len(t, cv.Folds, 4)
+	var sum float64
+	for _, f := range cv.Folds {
+		assert.Equal(t, 100, f.Epochs)
+		assert.Less(t, f.Metrics[entities.MetricLoss], 0.1)
+		sum += f.Metrics[entities.MetricLoss]
+	}
+	assert.InDelta(t, sum/4, cv.Mean[entities.MetricLoss], 1e-12)
+	assert.False(t, math.IsNaN(cv.Std[entities.MetricLoss]))
+	// Every fold trains a network of its own
+	assert.Zero(t, config.Seed)
+
+	// Cancellation aborts cross-validation
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = services.CrossValidateFolds(ctx, config, newTrainer, folds, 10)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/layer/neuron/synapse"
+	"main/internal/neural_net/application/services/solver"
+	"main/internal/neural_net/domain/entities"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func dropoutConfig(dropout float64) *entities.Config {
+	return &entities.Config{
+		Inputs:     1,
+		Layout:     []int{32, 1},
+		Activation: entities.ActivationTanh,
+		Mode:       entities.ModeRegression,
+		Weight:     synapse.NewUniform(1, 0),
+		Bias:       true,
+		Layers:     []entities.LayerConfig{{Dropout: dropout}, {Dropout: dropout}},
+	}
+}
+
+func Test_DropoutTraining(t *testing.T) {
+	rand.Seed(0)
+	exs := services.Examples{}
+	for x := 0.0; x < 1; x += 0.02 {
+		exs = append(exs, services.Example{Input: []float64{x}, Response: []float64{math.Sin(3 * x)}})
+	}
+
+	n := services.NewNeural(dropoutConfig(0.2))
+	assert.Equal(t, 0.2, n.Layers[0].Dropout)
+	assert.Equal(t, 0.0, n.Layers[1].Dropout)
+
+	trainer := services.NewBatchTrainer(solver.NewAdam(0.005, 0, 0, 0), 0, 10, 2)
+	trainer.Train(context.Background(), n, exs, nil, 1000)
+
+	assert.Less(t, services.CrossValidate(n, exs), 0.02)
+	for _, e := range exs {
+		assert.Equal(t, n.Predict(e.Input), n.Predict(e.Input))
+	}
+}
+
+func Test_DropoutDump(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(dropoutConfig(0.5))
+	exs := services.Examples{{Input: []float64{0.5}, Response: []float64{1}}}
+	services.NewTrainer(solver.NewSGD(0.01, 0, 0, false), 0).Train(context.Background(), n, exs, nil, 5)
+
+	plain := services.NewNeural(dropoutConfig(0))
+	plain.ApplyWeights(n.Dump().Weights)
+	for _, x := range []float64{0, 0.3, 0.9} {
+		assert.Equal(t, plain.Predict([]float64{x}), n.Predict([]float64{x}))
+	}
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/layer/neuron/synapse"
+	"main/internal/neural_net/application/services/solver"
+	"main/internal/neural_net/domain/entities"
+	"math/rand"
+	"testing"
+)
+
+func Test_EarlyStoppingLoss(t *testing.T) {
+	rand.Seed(0)
+	// A linear model cannot fit xor, so the validation loss stalls
+	n := services.NewNeural(&entities.Config{
+		Inputs:     2,
+		Layout:     []int{1},
+		Activation: entities.ActivationTanh,
+		Mode:       entities.ModeBinary,
+		Bias:       true,
+	})
+
+	trainer := services.NewTrainer(solver.NewSGD(0.5, 0, 0, false), 0).
+		WithEarlyStopping(entities.EarlyStopping{Patience: 5, MinDelta: 1e-4})
+	result, err := trainer.Train(context.Background(), n, xor, xor, 1000)
+	assert.Nil(t, err)
+
+	assert.Equal(t, entities.StopEarly, result.Stop)
+	assert.Less(t, result.Epochs, 1000)
+	assert.Equal(t, result.BestEpoch+5, result.Epochs)
+	assert.Equal(t, result.Best, services.CrossValidate(n, xor))
+}
+
+func Test_EarlyStoppingAccuracy(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(&entities.Config{
+		Inputs:     2,
+		Layout:     []int{8, 2},
+		Activation: entities.ActivationTanh,
+		Mode:       entities.ModeMultiClass,
+		Weight:     synapse.NewUniform(1, 0),
+		Bias:       true,
+	})
+	onehot := services.Examples{
+		{Input: []float64{0, 0}, Response: []float64{1, 0}},
+		{Input: []float64{1, 0}, Response: []float64{0, 1}},
+		{Input: []float64{0, 1}, Response: []float64{0, 1}},
+		{Input: []float64{1, 1}, Response: []float64{1, 0}},
+	}
+
+	trainer := services.NewBatchTrainer(solver.NewAdam(0.05, 0, 0, 0), 0, 4, 1).
+		WithEarlyStopping(entities.EarlyStopping{Monitor: entities.MetricAccuracy, Patience: 50})
+	result, err := trainer.Train(context.Background(), n, onehot, onehot, 1000)
+	assert.Nil(t, err)
+
+	assert.Equal(t, entities.StopEarly, result.Stop)
+	assert.Equal(t, 1.0, result.Best)
+	assert.Equal(t, 1.0, services.Accuracy(n, onehot))
+	assert.Equal(t, result.BestEpoch+50, result.Epochs)
+}
+
+func Test_EarlyStoppingRestoresBest(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(dropoutConfig(0))
+	exs := services.Examples{}
+	for x := 0.0; x < 1; x += 0.1 {
+		exs = append(exs, services.Example{Input: []float64{x}, Response: []float64{x}})
+	}
+	validation := services.Examples{{Input: []float64{0.55}, Response: []float64{2}}}
+
+	// The validation example contradicts the training ones, so the
+	// validation loss gets worse as training progresses
+	trainer := services.NewTrainer(solver.NewSGD(0.05, 0.9, 0, false), 0).
+		WithEarlyStopping(entities.EarlyStopping{Patience: 20})
+	result, err := trainer.Train(context.Background(), n, exs, validation, 60)
+	assert.Nil(t, err)
+
+	assert.InDelta(t, result.Best, services.CrossValidate(n, validation), 1e-12)
+	assert.Less(t, result.BestEpoch, result.Epochs)
+}
+
+func Test_TrainCompleted(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(normConfig(entities.NormNone))
+	result, err := services.NewTrainer(solver.NewSGD(0.1, 0, 0, false), 0).Train(context.Background(), n, xor, nil, 7)
+	assert.Nil(t, err)
+	assert.Equal(t, &services.Result{Epochs: 7, Stop: entities.StopCompleted}, result)
+}
+
+func Test_EarlyStoppingNeedsValidation(t *testing.T) {
+	n := services.NewNeural(normConfig(entities.NormNone))
+	trainer := services.NewTrainer(solver.NewSGD(0.1, 0, 0, false), 0).WithEarlyStopping(entities.EarlyStopping{})
+	_, err := trainer.Train(context.Background(), n, xor, nil, 10)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/domain/entities"
+	"math/rand"
+	"testing"
+)
+
+// gradientExamples draws examples of the given size, with responses suited
+// to mode
+func gradientExamples(rng *rand.Rand, size, inputs, outputs int, mode entities.Mode) services.Examples {
+	examples := make(services.Examples, size)
+	for i := range examples {
+		e := services.Example{Input: make([]float64, inputs), Response: make([]float64, outputs)}
+		for k := range e.Input {
+			e.Input[k] = rng.NormFloat64()
+		}
+		switch mode {
+		case entities.ModeMultiClass:
+			e.Response[rng.Intn(outputs)] = 1
+		case entities.ModeBinary, entities.ModeMultiLabel:
+			for j := range e.Response {
+				e.Response[j] = float64(rng.Intn(2))
+			}
+		default:
+			for j := range e.Response {
+				e.Response[j] = rng.NormFloat64()
+			}
+		}
+		examples[i] = e
+	}
+	return examples
+}
+
+func Test_GradientCheck(t *testing.T) {
+	activations := []entities.ActivationType{
+		entities.ActivationSigmoid, entities.ActivationTanh, entities.ActivationReLU,
+		entities.ActivationLinear, entities.ActivationLeakyReLU, entities.ActivationELU,
+		entities.ActivationSELU, entities.ActivationGELU, entities.ActivationSwish,
+		entities.ActivationSoftplus, entities.ActivationHardSigmoid,
+	}
+	// Every loss with the modes whose output activation it supports
+	losses := []struct {
+		loss  entities.LossType
+		modes []entities.Mode
+	}{
+		{entities.LossCrossEntropy, []entities.Mode{entities.ModeMultiClass}},
+		{entities.LossFocal, []entities.Mode{entities.ModeMultiClass}},
+		{entities.LossBinaryCrossEntropy, []entities.Mode{entities.ModeBinary, entities.ModeMultiLabel}},
+		{entities.LossBinaryFocal, []entities.Mode{entities.ModeBinary, entities.ModeMultiLabel}},
+		{entities.LossMeanSquared, []entities.Mode{entities.ModeRegression, entities.ModeBinary}},
+		{entities.LossHuber, []entities.Mode{entities.ModeRegression}},
+		{entities.LossMeanAbsolute, []entities.Mode{entities.ModeRegression}},
+		{entities.LossLogCosh, []entities.Mode{entities.ModeRegression, entities.ModeMultiLabel}},
+		{entities.LossQuantile, []entities.Mode{entities.ModeRegression}},
+		// The default loss of every mode
+		{entities.LossNone, []entities.Mode{
+			entities.ModeRegression, entities.ModeBinary, entities.ModeMultiClass, entities.ModeMultiLabel,
+		}},
+	}
+	outputs := map[entities.Mode]int{
+		entities.ModeRegression: 2, entities.ModeBinary: 1, entities.ModeMultiClass: 3, entities.ModeMultiLabel: 2,
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for _, a := range activations {
+		for _, l := range losses {
+			for _, mode := range l.modes {
+				n := services.NewNeural(&entities.Config{
+					Inputs:     3,
+					Layout:     []int{4, outputs[mode]},
+					Activation: a,
+					Mode:       mode,
+					Loss:       l.loss,
+					Bias:       true,
+					Seed:       1,
+				})
+				examples := gradientExamples(rng, 4, 3, outputs[mode], mode)
+				var loss services.Loss
+				if l.loss == entities.LossMeanSquared || l.loss == entities.LossNone && mode == entities.ModeRegression {
+					loss = halfSumSquared{}
+				}
+				worst := services.CheckGradients(n, loss, examples, 0).Worst()
+				assert.Less(t, worst.Relative, 1e-4, "activation %d, %s, mode %d: %+v", a, l.loss, mode, worst)
+			}
+		}
+	}
+}
+
+func Test_GradientCheckTraining(t *testing.T) {
+	// Batch normalization, dropout and penalties are part of the gradient
+	n := services.NewNeural(&entities.Config{
+		Inputs: 3,
+		Layout: []int{5, 4, 3},
+		Layers: []entities.LayerConfig{
+			{Norm: entities.NormBatch, Dropout: 0.3},
+			{Norm: entities.NormLayer},
+			{},
+		},
+		Activation: entities.ActivationTanh,
+		Mode:       entities.ModeMultiClass,
+		Bias:       true,
+		L1:         0.01,
+		L2:         0.1,
+		Seed:       1,
+	})
+	examples := gradientExamples(rand.New(rand.NewSource(1)), 6, 3, 3, entities.ModeMultiClass)
+	weights := n.Weights()
+	check := services.CheckGradients(n, nil, examples, 0)
+
+	assert.Len(t, check, n.NumWeights())
+	assert.Less(t, check.Worst().Relative, 1e-4, "%+v", check.Worst())
+	assert.Equal(t, weights, n.Weights())
+}
+
+// halfSumSquared is the loss whose derivative MeanSquared.Df is: half the
+// squared errors summed over outputs, averaged over examples
+type halfSumSquared struct {
+	services.MeanSquared
+}
+
+func (l halfSumSquared) F(estimate, ideal [][]float64) float64 {
+	return l.MeanSquared.F(estimate, ideal) * float64(len(estimate[0])) / 2
+}
+
+// halfMeanSquared forgets the factor 2 of the derivative of MeanSquared's F
+type halfMeanSquared struct {
+	services.MeanSquared
+}
+
+func (halfMeanSquared) Df(estimate, ideal, activation, delta []float64) {
+	for j := range delta {
+		delta[j] = activation[j] * (estimate[j] - ideal[j]) / float64(len(delta))
+	}
+}
+
+func Test_GradientCheckFindsErrors(t *testing.T) {
+	n := services.NewNeural(&entities.Config{
+		Inputs:     3,
+		Layout:     []int{4, 2},
+		Activation: entities.ActivationTanh,
+		Mode:       entities.ModeRegression,
+		Bias:       true,
+		Seed:       1,
+	})
+	examples := gradientExamples(rand.New(rand.NewSource(1)), 4, 3, 2, entities.ModeRegression)
+
+	worst := services.CheckGradients(n, halfMeanSquared{}, examples, 0).Worst()
+	assert.InDelta(t, 0.5, worst.Relative, 1e-4)
+	assert.InDelta(t, worst.Numeric/2, worst.Analytic, 1e-6)
+}
@@ -0,0 +1,243 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/layer/neuron/synapse"
+	"main/internal/neural_net/application/services/solver"
+	"main/internal/neural_net/domain/entities"
+	nnErrors "main/internal/neural_net/domain/errors"
+	"main/internal/neural_net/domain/utils"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func Test_RegressionLossDerivatives(t *testing.T) {
+	const h = 1e-6
+	losses := []services.Loss{
+		halfSumSquared{},
+		services.Huber{Delta: 0.5},
+		services.MeanAbsolute{},
+		services.LogCosh{},
+		services.Quantile{Quantiles: []float64{0.1, 0.5, 0.9}},
+	}
+	estimate := []float64{0.3, -1.2, 2.5}
+	ideal := []float64{0.1, 0.4, 2}
+	activation := []float64{1, 1, 1}
+
+	for _, loss := range losses {
+		delta := make([]float64, len(estimate))
+		loss.Df(estimate, ideal, activation, delta)
+		for j := range estimate {
+			up := append([]float64{}, estimate...)
+			down := append([]float64{}, estimate...)
+			up[j] += h
+			down[j] -= h
+			numeric := (loss.F([][]float64{up}, [][]float64{ideal}) - loss.F([][]float64{down}, [][]float64{ideal})) / (2 * h)
+			assert.InDelta(t, numeric, delta[j], 1e-5, "%T output %d", loss, j)
+		}
+	}
+}
+
+func Test_HuberLimits(t *testing.T) {
+	huber := services.Huber{Delta: 1}
+	assert.InDelta(t, 0.125, huber.F([][]float64{{0.5}}, [][]float64{{0}}), 1e-12)
+	assert.InDelta(t, 9.5, huber.F([][]float64{{10}}, [][]float64{{0}}), 1e-12)
+	assert.InDelta(t, 0, services.LogCosh{}.F([][]float64{{3}}, [][]float64{{3}}), 1e-12)
+	assert.InDelta(t, 999-math.Ln2, services.LogCosh{}.F([][]float64{{1000}}, [][]float64{{1}}), 1e-9)
+}
+
+func Test_QuantileRegression(t *testing.T) {
+	rand.Seed(0)
+	quantiles := []float64{0.1, 0.5, 0.9}
+	data := services.Examples{}
+	for i := 0; i < 500; i++ {
+		x := rand.Float64()
+		y := x + rand.Float64() - 0.5
+		data = append(data, services.Example{Input: []float64{x}, Response: []float64{y, y, y}})
+	}
+
+	n := services.NewNeural(&entities.Config{
+		Inputs:     1,
+		Layout:     []int{8, 3},
+		Activation: entities.ActivationTanh,
+		Mode:       entities.ModeRegression,
+		Loss:       entities.LossQuantile,
+		Quantiles:  quantiles,
+		Seed:       2,
+		Weight:     synapse.NewUniform(0.5, 0),
+		Bias:       true,
+	})
+	trainer := services.NewTrainer(solver.NewSGD(0.01, 0.5, 0, false), 0)
+	trainer.Train(context.Background(), n, data, nil, 200)
+
+	for _, x := range []float64{0.2, 0.5, 0.8} {
+		est := n.Predict([]float64{x})
+		for j, q := range quantiles {
+			assert.InDelta(t, x+q-0.5, est[j], 0.1, "quantile %.1f at %.1f", q, x)
+		}
+	}
+}
+
+func Test_HuberRegression(t *testing.T) {
+	rand.Seed(0)
+	data := services.Examples{}
+	for i := 0; i < 200; i++ {
+		x := rand.Float64()
+		y := 2 * x
+		if i%20 == 0 {
+			y += 50
+		}
+		data = append(data, services.Example{Input: []float64{x}, Response: []float64{y}})
+	}
+
+	n := services.NewNeural(&entities.Config{
+		Inputs:     1,
+		Layout:     []int{1},
+		Mode:       entities.ModeRegression,
+		Loss:       entities.LossHuber,
+		HuberDelta: 0.5,
+		Weight:     synapse.NewUniform(0.5, 0),
+	})
+	trainer := services.NewTrainer(solver.NewSGD(0.01, 0, 0, false), 0)
+	trainer.Train(context.Background(), n, data, nil, 300)
+
+	assert.InDelta(t, 2, n.Weights()[0][0][0], 0.25)
+}
+
+// logitGradient numerically differentiates the loss of one example with
+// respect to the logits z, mapped to outputs by squash
+func logitGradient(loss services.Loss, z, ideal []float64, squash func([]float64) []float64) []float64 {
+	const h = 1e-6
+	grad := make([]float64, len(z))
+	for k := range z {
+		up := append([]float64{}, z...)
+		down := append([]float64{}, z...)
+		up[k] += h
+		down[k] -= h
+		grad[k] = (loss.F([][]float64{squash(up)}, [][]float64{ideal}) -
+			loss.F([][]float64{squash(down)}, [][]float64{ideal})) / (2 * h)
+	}
+	return grad
+}
+
+func sigmoids(z []float64) []float64 {
+	out := make([]float64, len(z))
+	for i, v := range z {
+		out[i] = 1 / (1 + math.Exp(-v))
+	}
+	return out
+}
+
+func Test_ClassificationLossDerivatives(t *testing.T) {
+	z := []float64{0.3, -1.2, 1.7}
+	weights := []float64{0.5, 3, 1}
+
+	for _, loss := range []services.Loss{
+		services.CrossEntropy{Weights: weights},
+		services.Focal{Gamma: 2},
+		services.Focal{Gamma: 0.5, Weights: weights},
+	} {
+		ideal := []float64{0, 1, 0}
+		p := utils.Softmax(z)
+		delta := make([]float64, len(z))
+		loss.Df(p, ideal, nil, delta)
+		assert.InDeltaSlice(t, logitGradient(loss, z, ideal, utils.Softmax), delta, 1e-6, "%+v", loss)
+	}
+
+	for _, loss := range []services.Loss{
+		services.BinaryCrossEntropy{Weights: []float64{1, 4}},
+		services.BinaryFocal{Gamma: 2},
+		services.BinaryFocal{Gamma: 1.5, Alpha: 0.25, Weights: []float64{2, 1}},
+	} {
+		ideal := []float64{1, 0, 1}
+		delta := make([]float64, len(z))
+		loss.Df(sigmoids(z), ideal, nil, delta)
+		assert.InDeltaSlice(t, logitGradient(loss, z, ideal, sigmoids), delta, 1e-6, "%+v", loss)
+	}
+}
+
+func Test_WeightedCrossValidate(t *testing.T) {
+	rand.Seed(0)
+	config := func(weights []float64) *entities.Config {
+		return &entities.Config{
+			Inputs:       2,
+			Layout:       []int{2},
+			Mode:         entities.ModeBinary,
+			Weight:       synapse.NewUniform(0.5, 0),
+			ClassWeights: weights,
+		}
+	}
+	n := services.NewNeural(config(nil))
+	weighted := services.NewNeural(config([]float64{2, 2}))
+	weighted.ApplyWeights(n.Weights())
+
+	exs := services.Examples{{Input: []float64{1, 0}, Response: []float64{1, 0}}}
+	assert.InDelta(t, 2*services.CrossValidate(n, exs), services.CrossValidate(weighted, exs), 1e-12)
+}
+
+func Test_FocalTraining(t *testing.T) {
+	rand.Seed(0)
+	var exs services.Examples
+	for i := 0; i < 200; i++ {
+		x := rand.Float64()*2 - 1
+		y := []float64{1, 0, 0}
+		if x > 0.6 {
+			y = []float64{0, 0, 1}
+		} else if x > -0.6 {
+			y = []float64{0, 1, 0}
+		}
+		exs = append(exs, services.Example{Input: []float64{x}, Response: y})
+	}
+
+	n := services.NewNeural(&entities.Config{
+		Inputs:       1,
+		Layout:       []int{8, 3},
+		Activation:   entities.ActivationTanh,
+		Mode:         entities.ModeMultiClass,
+		Loss:         entities.LossFocal,
+		ClassWeights: []float64{2, 1, 2},
+		Weight:       synapse.NewUniform(1, 0),
+		Bias:         true,
+	})
+	trainer := services.NewTrainer(solver.NewAdam(0.01, 0, 0, 0), 0)
+	trainer.Train(context.Background(), n, exs, nil, 200)
+
+	assert.Greater(t, services.Accuracy(n, exs), 0.9)
+}
+
+func Test_LossParamsLength(t *testing.T) {
+	c := &entities.Config{Inputs: 1, Layout: []int{4, 3}, Loss: entities.LossQuantile, Quantiles: []float64{0.1, 0.9}}
+	assert.True(t, errors.Is(services.CheckConfig(c), nnErrors.ErrInvalidConfig))
+	assert.Panics(t, func() { services.NewNeural(c) })
+
+	for _, q := range [][]float64{nil, {0.9}, {0.1, 0.5, 0.9}} {
+		c.Quantiles = q
+		assert.NoError(t, services.CheckConfig(c))
+	}
+
+	c = &entities.Config{Inputs: 1, Layout: []int{3}, Mode: entities.ModeMultiClass, ClassWeights: []float64{1, 2}}
+	assert.Panics(t, func() { services.NewNeural(c) })
+	c.Loss, c.ClassWeights = entities.LossBinaryFocal, []float64{1, 2, 3}
+	assert.True(t, errors.Is(services.CheckConfig(c), nnErrors.ErrInvalidConfig))
+	c.ClassWeights = []float64{1, 2}
+	assert.NoError(t, services.CheckConfig(c))
+	c.Loss, c.ClassWeights = entities.LossFocal, []float64{1, 2, 3}
+	assert.NoError(t, services.CheckConfig(c))
+}
+
+func Test_CrossEntropyNeedsSoftmax(t *testing.T) {
+	c := &entities.Config{Inputs: 1, Layout: []int{2}, Mode: entities.ModeMultiLabel, Loss: entities.LossCrossEntropy}
+	assert.True(t, errors.Is(services.CheckConfig(c), nnErrors.ErrInvalidConfig))
+	assert.Panics(t, func() { services.NewNeural(c) })
+	c.Loss = entities.LossFocal
+	assert.True(t, errors.Is(services.CheckConfig(c), nnErrors.ErrInvalidConfig))
+
+	c.Mode = entities.ModeMultiClass
+	assert.NoError(t, services.CheckConfig(c))
+	c.Mode, c.Activation = entities.ModeDefault, entities.ActivationSoftmax
+	assert.NoError(t, services.CheckConfig(c))
+}
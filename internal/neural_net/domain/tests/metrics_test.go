@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/metrics"
+	"main/internal/neural_net/domain/entities"
+	"math"
+	"testing"
+)
+
+func Test_BinaryMetrics(t *testing.T) {
+	estimate := [][]float64{{0.9}, {0.4}, {0.6}, {0.2}}
+	ideal := [][]float64{{1}, {1}, {0}, {0}}
+	d := metrics.Decision{Mode: entities.ModeBinary}
+
+	assert.Equal(t, 0.5, metrics.Accuracy(d, estimate, ideal))
+	assert.Equal(t, 0.5, metrics.Precision(d, estimate, ideal, metrics.Macro))
+	assert.Equal(t, 0.5, metrics.Recall(d, estimate, ideal, metrics.Macro))
+	assert.Equal(t, 0.5, metrics.F1(d, estimate, ideal, metrics.Macro))
+	assert.Equal(t, [][]int{{1, 1}, {1, 1}}, metrics.ConfusionMatrix(d, estimate, ideal))
+	assert.Equal(t, 0.75, metrics.ROCAUC(estimate, ideal))
+	assert.InDelta(t, 0.5+0.5*2.0/3, metrics.PRAUC(estimate, ideal), 1e-12)
+	logLoss := -(math.Log(0.9) + math.Log(0.4) + math.Log(0.4) + math.Log(0.8)) / 4
+	assert.InDelta(t, logLoss, metrics.LogLoss(d.Mode, estimate, ideal), 1e-12)
+
+	// Ties count half in ROC-AUC, outputs of a single class are undefined
+	assert.Equal(t, 0.5, metrics.ROCAUC([][]float64{{0.5}, {0.5}}, [][]float64{{1}, {0}}))
+	assert.True(t, math.IsNaN(metrics.ROCAUC(estimate, [][]float64{{1}, {1}, {1}, {1}})))
+}
+
+func Test_MultiClassMetrics(t *testing.T) {
+	estimate := [][]float64{{.7, .2, .1}, {.1, .8, .1}, {.3, .4, .3}, {.2, .2, .6}, {.5, .3, .2}}
+	ideal := [][]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {0, 0, 1}, {0, 1, 0}}
+	d := metrics.Decision{Mode: entities.ModeMultiClass}
+
+	assert.Equal(t, 0.6, metrics.Accuracy(d, estimate, ideal))
+	assert.Equal(t, [][]int{{1, 0, 0}, {1, 1, 0}, {0, 1, 1}}, metrics.ConfusionMatrix(d, estimate, ideal))
+	assert.InDelta(t, 2.0/3, metrics.Precision(d, estimate, ideal, metrics.Macro), 1e-12)
+	assert.InDelta(t, 2.0/3, metrics.Recall(d, estimate, ideal, metrics.Macro), 1e-12)
+	assert.InDelta(t, 11.0/18, metrics.F1(d, estimate, ideal, metrics.Macro), 1e-12)
+	// Micro-averaged scores of single-label examples are the accuracy
+	for _, m := range []entities.MetricType{entities.MetricMicroPrecision, entities.MetricMicroRecall, entities.MetricMicroF1} {
+		assert.InDelta(t, 0.6, metrics.Evaluate(m, d, estimate, ideal), 1e-12, m.String())
+	}
+	logLoss := -(math.Log(.7) + math.Log(.8) + math.Log(.3) + math.Log(.6) + math.Log(.3)) / 5
+	assert.InDelta(t, logLoss, metrics.LogLoss(d.Mode, estimate, ideal), 1e-12)
+}
+
+func Test_MultiLabelMetrics(t *testing.T) {
+	estimate := [][]float64{{.8, .6}, {.3, .9}, {.7, .2}}
+	ideal := [][]float64{{1, 0}, {0, 1}, {1, 1}}
+	d := metrics.Decision{Mode: entities.ModeMultiLabel}
+
+	assert.InDelta(t, 1.0/3, metrics.Accuracy(d, estimate, ideal), 1e-12)
+	assert.Equal(t, []metrics.Counts{
+		{TruePositives: 2, TrueNegatives: 1},
+		{TruePositives: 1, FalsePositives: 1, FalseNegatives: 1},
+	}, metrics.ClassCounts(d, estimate, ideal))
+	assert.Equal(t, 0.75, metrics.F1(d, estimate, ideal, metrics.Macro))
+	assert.Equal(t, 0.75, metrics.Precision(d, estimate, ideal, metrics.Micro))
+	assert.Equal(t, 0.75, metrics.ROCAUC(estimate, ideal))
+	assert.InDelta(t, (1+5.0/6)/2, metrics.PRAUC(estimate, ideal), 1e-12)
+}
+
+func Test_RegressionMetrics(t *testing.T) {
+	estimate := [][]float64{{2}, {4}, {2}, {4}}
+	ideal := [][]float64{{1}, {3}, {6}, {5}}
+
+	assert.Equal(t, 1.75, metrics.MAE(estimate, ideal))
+	assert.Equal(t, math.Sqrt(4.75), metrics.RMSE(estimate, ideal))
+	assert.InDelta(t, 1-19/14.75, metrics.R2(estimate, ideal), 1e-12)
+	assert.InDelta(t, 0.55, metrics.MAPE(estimate, ideal), 1e-12)
+	assert.InDelta(t, 2.0/3, metrics.DirectionalAccuracy(estimate, ideal), 1e-12)
+	assert.Equal(t, 1.0, metrics.R2(ideal, ideal))
+}
+
+func Test_EvaluateMetrics(t *testing.T) {
+	n := services.NewNeural(&entities.Config{
+		Inputs:     2,
+		Layout:     []int{4, 1},
+		Activation: entities.ActivationTanh,
+		Mode:       entities.ModeBinary,
+		Bias:       true,
+	})
+	estimate, ideal := make([][]float64, len(xor)), make([][]float64, len(xor))
+	for i, e := range xor {
+		estimate[i], ideal[i] = n.Predict(e.Input), e.Response
+	}
+
+	assert.Equal(t, metrics.Accuracy(n.Decision(), estimate, ideal), services.Accuracy(n, xor))
+	assert.Equal(t, metrics.ROCAUC(estimate, ideal), services.Evaluate(n, xor, entities.MetricROCAUC))
+	assert.Equal(t, services.CrossValidate(n, xor), services.Evaluate(n, xor, entities.MetricLoss))
+	assert.Equal(t, metrics.ConfusionMatrix(n.Decision(), estimate, ideal), services.ConfusionMatrix(n, xor))
+
+	assert.True(t, entities.MetricR2.HigherIsBetter())
+	assert.False(t, entities.MetricLogLoss.HigherIsBetter())
+}
@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/layer/neuron/synapse"
+	"main/internal/neural_net/domain/entities"
+	"math/rand"
+	"testing"
+)
+
+func Test_LayerConfig(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(&entities.Config{
+		Inputs:     2,
+		Layout:     []int{4, 3, 1},
+		Activation: entities.ActivationSigmoid,
+		Mode:       entities.ModeRegression,
+		Weight:     synapse.NewUniform(0.5, 0),
+		Bias:       true,
+		Layers: []entities.LayerConfig{
+			{Activation: entities.ActivationReLU},
+			{Bias: entities.Bool(false), Weight: synapse.NewNormal(1, 0)},
+		},
+	})
+
+	assert.Equal(t, entities.ActivationReLU, n.Layers[0].A)
+	assert.Equal(t, entities.ActivationSigmoid, n.Layers[1].A)
+	assert.Equal(t, entities.ActivationLinear, n.Layers[2].A)
+	assert.True(t, n.Layers[0].Bias)
+	assert.False(t, n.Layers[1].Bias)
+	assert.False(t, n.Layers[2].Bias)
+	assert.Equal(t, 4*3+3*4+1*3, n.NumWeights())
+	assert.Len(t, n.Config.Layers, 3)
+}
+
+func Test_LayerConfigDump(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(&entities.Config{
+		Inputs:     2,
+		Layout:     []int{4, 4, 2},
+		Activation: entities.ActivationTanh,
+		Mode:       entities.ModeMultiClass,
+		Bias:       true,
+		Layers: []entities.LayerConfig{
+			{Activation: entities.ActivationReLU, Bias: entities.Bool(false)},
+		},
+	})
+
+	blob, err := n.Marshal()
+	assert.NoError(t, err)
+	restored, err := services.Unmarshal(blob)
+	assert.NoError(t, err)
+
+	for i, l := range restored.Layers {
+		assert.Equal(t, n.Layers[i].A, l.A)
+		assert.Equal(t, n.Layers[i].Bias, l.Bias)
+	}
+	for _, x := range [][]float64{{0, 0}, {0.5, -1}, {2, 3}} {
+		assert.Equal(t, n.Predict(x), restored.Predict(x))
+	}
+}
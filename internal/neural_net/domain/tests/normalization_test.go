@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/layer/neuron/synapse"
+	"main/internal/neural_net/application/services/solver"
+	"main/internal/neural_net/domain/entities"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func normConfig(norm entities.NormType) *entities.Config {
+	return &entities.Config{
+		Inputs:     2,
+		Layout:     []int{8, 1},
+		Activation: entities.ActivationTanh,
+		Mode:       entities.ModeBinary,
+		Weight:     synapse.NewUniform(0.5, 0),
+		Bias:       true,
+		Layers:     []entities.LayerConfig{{Norm: norm}},
+	}
+}
+
+var xor = services.Examples{
+	{Input: []float64{0, 0}, Response: []float64{0}},
+	{Input: []float64{1, 0}, Response: []float64{1}},
+	{Input: []float64{0, 1}, Response: []float64{1}},
+	{Input: []float64{1, 1}, Response: []float64{0}},
+}
+
+func Test_NormNumWeights(t *testing.T) {
+	for _, norm := range []entities.NormType{entities.NormBatch, entities.NormLayer} {
+		n := services.NewNeural(normConfig(norm))
+		assert.Equal(t, (2+1)*8+2*8+(8+1)*1, n.NumWeights())
+		assert.Equal(t, []float64{1, 1, 1, 1, 1, 1, 1, 1}, n.Layers[0].Gamma)
+		assert.Equal(t, make([]float64, 8), n.Layers[0].Beta)
+		assert.Nil(t, n.Layers[1].Gamma)
+	}
+	assert.Nil(t, services.NewNeural(normConfig(entities.NormLayer)).Layers[0].Mean)
+}
+
+func Test_LayerNormForward(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(&entities.Config{
+		Inputs:     3,
+		Layout:     []int{6},
+		Activation: entities.ActivationLinear,
+		Weight:     synapse.NewUniform(1, 0),
+		Bias:       true,
+		Layers:     []entities.LayerConfig{{Norm: entities.NormLayer}},
+	})
+
+	out := n.Predict([]float64{0.3, -2, 5})
+	var mean, variance float64
+	for _, v := range out {
+		mean += v / 6
+	}
+	for _, v := range out {
+		variance += (v - mean) * (v - mean) / 6
+	}
+	assert.InDelta(t, 0, mean, 1e-9)
+	assert.InDelta(t, 1, variance, 1e-4)
+}
+
+func Test_LayerNormTraining(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(normConfig(entities.NormLayer))
+	services.NewTrainer(solver.NewAdam(0.01, 0, 0, 0), 0).Train(context.Background(), n, xor, nil, 500)
+
+	for _, e := range xor {
+		assert.InDelta(t, e.Response[0], n.Predict(e.Input)[0], 0.1)
+	}
+}
+
+func Test_BatchNormTraining(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(normConfig(entities.NormBatch))
+	services.NewBatchTrainer(solver.NewAdam(0.01, 0, 0, 0), 0, 4, 1).Train(context.Background(), n, xor, nil, 1000)
+
+	// Inference uses the running statistics, so predictions do not depend
+	// on the other rows of a batch
+	for _, e := range xor {
+		assert.InDelta(t, e.Response[0], n.Predict(e.Input)[0], 0.1)
+	}
+	l := n.Layers[0]
+	for j := range l.Mean {
+		assert.NotEqual(t, 0.0, l.Mean[j])
+		assert.NotEqual(t, 1.0, l.Var[j])
+		assert.False(t, math.IsNaN(l.Var[j]))
+	}
+}
+
+func Test_NormDump(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(normConfig(entities.NormBatch))
+	services.NewBatchTrainer(solver.NewAdam(0.01, 0, 0, 0), 0, 4, 1).Train(context.Background(), n, xor, nil, 20)
+
+	dump := n.Dump()
+	assert.Nil(t, dump.Norms[1])
+	assert.Equal(t, n.Layers[0].Gamma, dump.Norms[0].Gamma)
+	assert.Equal(t, n.Layers[0].Var, dump.Norms[0].Var)
+
+	b, err := n.Marshal()
+	assert.Nil(t, err)
+	restored, err := services.Unmarshal(b)
+	assert.Nil(t, err)
+	assert.Equal(t, n.NumWeights(), restored.NumWeights())
+	for _, e := range xor {
+		assert.Equal(t, n.Predict(e.Input), restored.Predict(e.Input))
+	}
+
+	plain, err := services.NewNeural(normConfig(entities.NormNone)).Marshal()
+	assert.Nil(t, err)
+	assert.NotContains(t, string(plain), "Norms")
+}
+
+func Test_BatchNormSingleRow(t *testing.T) {
+	n := services.NewNeural(normConfig(entities.NormBatch))
+	// Online, a trailing batch of 1 and shards of 1
+	for _, trainer := range []*services.BatchTrainer{
+		services.NewTrainer(solver.NewAdam(0.01, 0, 0, 0), 0),
+		services.NewBatchTrainer(solver.NewAdam(0.01, 0, 0, 0), 0, 3, 1),
+		services.NewBatchTrainer(solver.NewAdam(0.01, 0, 0, 0), 0, 2, 2),
+	} {
+		_, err := trainer.Train(context.Background(), n, xor, xor, 1)
+		assert.Error(t, err)
+	}
+
+	_, err := services.NewBatchTrainer(solver.NewAdam(0.01, 0, 0, 0), 0, 4, 2).Train(context.Background(), n, xor, xor, 1)
+	assert.NoError(t, err)
+}
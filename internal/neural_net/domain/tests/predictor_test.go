@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/layer/neuron/synapse"
+	"main/internal/neural_net/domain/entities"
+	nnErrors "main/internal/neural_net/domain/errors"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func newPredictorNet() *services.Neural {
+	return services.NewNeural(&entities.Config{
+		Inputs:     3,
+		Layout:     []int{8, 8, 4},
+		Activation: entities.ActivationTanh,
+		Mode:       entities.ModeMultiClass,
+		Weight:     synapse.NewNormal(0.5, 0),
+		Bias:       true,
+	})
+}
+
+func Test_PredictorMatchesNeural(t *testing.T) {
+	rand.Seed(0)
+	n := newPredictorNet()
+	p := services.NewPredictor(n)
+
+	inputs := make([][]float64, 100)
+	outs := make([][]float64, len(inputs))
+	for i := range inputs {
+		inputs[i] = []float64{rand.Float64(), rand.Float64(), rand.Float64()}
+		outs[i] = make([]float64, p.Outputs())
+	}
+	assert.NoError(t, p.PredictBatch(inputs, outs))
+
+	out := make([]float64, p.Outputs())
+	for i, input := range inputs {
+		assert.NoError(t, p.Predict(input, out))
+		assert.Equal(t, n.Predict(input), out)
+		assert.Equal(t, n.Predict(input), outs[i])
+	}
+
+	assert.Error(t, p.Predict([]float64{1}, out))
+	assert.Error(t, p.Predict(inputs[0], []float64{1}))
+}
+
+func Test_PredictorFromDump(t *testing.T) {
+	rand.Seed(0)
+	n := newPredictorNet()
+	p, err := services.PredictorFromDump(n.Dump())
+	assert.NoError(t, err)
+
+	out := make([]float64, p.Outputs())
+	assert.NoError(t, p.Predict([]float64{0.1, 0.2, 0.3}, out))
+	assert.Equal(t, n.Predict([]float64{0.1, 0.2, 0.3}), out)
+
+	dump, config := n.Dump(), *n.Config
+	config.LossName = "missing"
+	dump.Config = &config
+	_, err = services.PredictorFromDump(dump)
+	assert.True(t, errors.Is(err, nnErrors.ErrUnknownLoss))
+}
+
+func Test_PredictorConcurrent(t *testing.T) {
+	rand.Seed(0)
+	n := newPredictorNet()
+	p := services.NewPredictor(n)
+	input := []float64{0.5, -0.5, 1}
+	want := n.Predict(input)
+
+	wg := sync.WaitGroup{}
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out := make([]float64, p.Outputs())
+			for i := 0; i < 1000; i++ {
+				p.Predict(input, out)
+				assert.Equal(t, want, out)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func Test_PredictorAllocations(t *testing.T) {
+	rand.Seed(0)
+	p := services.NewPredictor(newPredictorNet())
+	input := []float64{0.5, -0.5, 1}
+	out := make([]float64, p.Outputs())
+	p.Predict(input, out)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		p.Predict(input, out)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
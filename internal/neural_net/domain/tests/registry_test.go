@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/layer/neuron/synapse"
+	"main/internal/neural_net/application/services/solver"
+	"main/internal/neural_net/domain/entities"
+	nnErrors "main/internal/neural_net/domain/errors"
+	"main/internal/neural_net/domain/ports"
+	"main/internal/neural_net/domain/utils"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// bentIdentity is a custom activation used to exercise the registry
+type bentIdentity struct{}
+
+func (a bentIdentity) F(x float64) float64 { return (math.Sqrt(x*x+1)-1)/2 + x }
+
+func (a bentIdentity) Df(x, y float64) float64 { return x/(2*math.Sqrt(x*x+1)) + 1 }
+
+func init() {
+	utils.RegisterActivation("bent_identity", func(alpha float64) ports.Differentiable {
+		return bentIdentity{}
+	})
+	services.RegisterLoss("test_mse", services.MeanSquared{})
+}
+
+func Test_RegisteredActivationAndLoss(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(&entities.Config{
+		Inputs:         2,
+		Layout:         []int{4, 1},
+		ActivationName: "bent_identity",
+		LossName:       "test_mse",
+		Mode:           entities.ModeRegression,
+		Weight:         synapse.NewUniform(0.5, 0),
+		Bias:           true,
+	})
+	assert.Equal(t, "bent_identity", n.Config.Layers[0].ActivationName)
+	assert.Equal(t, "", n.Config.Layers[1].ActivationName)
+
+	trainer := services.NewTrainer(solver.NewSGD(0.01, 0.1, 0, false), 0)
+	trainer.Train(context.Background(), n, data, nil, 500)
+	assert.Less(t, services.CrossValidate(n, data), 0.1)
+
+	blob, err := n.Marshal()
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(string(blob), "bent_identity"))
+	restored, err := services.Unmarshal(blob)
+	assert.NoError(t, err)
+	for _, d := range data {
+		assert.Equal(t, n.Predict(d.Input), restored.Predict(d.Input))
+	}
+}
+
+func Test_UnknownRegisteredNames(t *testing.T) {
+	blob := []byte(`{"Config":{"Inputs":1,"Layout":[1],"ActivationName":"missing"},"Weights":[[[0]]]}`)
+	_, err := services.Unmarshal(blob)
+	assert.True(t, errors.Is(err, nnErrors.ErrUnknownActivation))
+
+	blob = []byte(`{"Config":{"Inputs":1,"Layout":[1],"LossName":"missing"},"Weights":[[[0]]]}`)
+	_, err = services.Unmarshal(blob)
+	assert.True(t, errors.Is(err, nnErrors.ErrUnknownLoss))
+
+	assert.Panics(t, func() {
+		services.NewNeural(&entities.Config{Inputs: 1, Layout: []int{1}, ActivationName: "missing"})
+	})
+}
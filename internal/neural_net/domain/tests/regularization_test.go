@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/layer/neuron/synapse"
+	"main/internal/neural_net/application/services/solver"
+	"main/internal/neural_net/domain/entities"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func regularizedNet(c entities.Config) *services.Neural {
+	c.Inputs = 2
+	c.Layout = []int{3, 1}
+	c.Activation = entities.ActivationTanh
+	c.Mode = entities.ModeBinary
+	c.Weight = synapse.NewUniform(1, 0)
+	c.Bias = true
+	return services.NewNeural(&c)
+}
+
+func weightNorm(n *services.Neural) (norm float64) {
+	for _, l := range n.Layers {
+		for k, w := range l.W {
+			if !l.IsBias(k) {
+				norm += w * w
+			}
+		}
+	}
+	return math.Sqrt(norm)
+}
+
+func Test_L2Regularization(t *testing.T) {
+	rand.Seed(0)
+	plain := regularizedNet(entities.Config{})
+	rand.Seed(0)
+	regularized := regularizedNet(entities.Config{L2: 0.05})
+
+	services.NewTrainer(solver.NewSGD(0.1, 0, 0, false), 0).Train(context.Background(), plain, data, nil, 300)
+	services.NewTrainer(solver.NewSGD(0.1, 0, 0, false), 0).Train(context.Background(), regularized, data, nil, 300)
+
+	assert.Less(t, weightNorm(regularized), weightNorm(plain))
+}
+
+func Test_RegularizedLoss(t *testing.T) {
+	rand.Seed(0)
+	n := regularizedNet(entities.Config{})
+	rand.Seed(0)
+	regularized := regularizedNet(entities.Config{L1: 0.1, L2: 0.2})
+
+	var l1, l2 float64
+	for _, l := range n.Layers {
+		for k, w := range l.W {
+			if !l.IsBias(k) {
+				l1 += math.Abs(w)
+				l2 += w * w
+			}
+		}
+	}
+	assert.InDelta(t, services.CrossValidate(n, data)+0.1*l1+0.1*l2,
+		services.CrossValidate(regularized, data), 1e-12)
+}
+
+func Test_WeightDecay(t *testing.T) {
+	rand.Seed(0)
+	n := regularizedNet(entities.Config{WeightDecay: 0.1})
+	before := n.Weights()
+
+	// A negligible learning rate leaves only the decay
+	trainer := services.NewTrainer(solver.NewSGD(1e-12, 0, 0, false), 0)
+	trainer.Train(context.Background(), n, data[:1], nil, 1)
+
+	after := n.Weights()
+	for i, l := range n.Layers {
+		for j := range after[i] {
+			for k := range after[i][j] {
+				want := 0.9 * before[i][j][k]
+				if l.IsBias(j*l.Stride() + k) {
+					want = before[i][j][k]
+				}
+				assert.InDelta(t, want, after[i][j][k], 1e-9)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/layer/neuron/synapse"
+	"main/internal/neural_net/application/services/solver"
+	"main/internal/neural_net/domain/entities"
+	"math/rand"
+	"testing"
+)
+
+func rates(s solver.Schedule, base float64, epochs int) []float64 {
+	r := make([]float64, epochs)
+	for i := range r {
+		r[i] = s.Rate(base, i+1)
+	}
+	return r
+}
+
+func Test_StepDecay(t *testing.T) {
+	assert.Equal(t, []float64{1, 1, 0.5, 0.5, 0.25}, rates(solver.NewStepDecay(2, 0.5), 1, 5))
+}
+
+func Test_ExponentialDecay(t *testing.T) {
+	assert.InDeltaSlice(t, []float64{2, 1.8, 1.62}, rates(solver.NewExponentialDecay(0.9), 2, 3), 1e-12)
+}
+
+func Test_CosineAnnealing(t *testing.T) {
+	r := rates(solver.NewCosineAnnealing(4, 2, 0.1), 1, 13)
+	assert.InDeltaSlice(t, []float64{1, 0.8682, 0.55, 0.2318}, r[:4], 1e-4)
+	// Restarts after 4 epochs, then after 8 more
+	assert.Equal(t, 1.0, r[4])
+	assert.Less(t, r[11], r[10])
+	assert.Equal(t, 1.0, r[12])
+}
+
+func Test_Warmup(t *testing.T) {
+	assert.Equal(t, []float64{0.25, 0.5, 0.75, 1, 1}, rates(solver.NewWarmup(4, nil), 1, 5))
+	r := rates(solver.NewWarmup(2, solver.NewStepDecay(1, 0.5)), 1, 4)
+	assert.Equal(t, []float64{0.5, 1, 1, 0.5}, r)
+}
+
+func Test_OneCycle(t *testing.T) {
+	r := rates(solver.NewOneCycle(10, 0.2, 10, 100), 1, 10)
+	assert.InDelta(t, 0.1, r[0], 1e-12)
+	assert.InDelta(t, 1, r[2], 1e-12)
+	assert.InDelta(t, 0.001, r[9], 1e-12)
+	for i := 1; i <= 2; i++ {
+		assert.Greater(t, r[i], r[i-1])
+	}
+	for i := 3; i < len(r); i++ {
+		assert.Less(t, r[i], r[i-1])
+	}
+}
+
+func Test_ReduceOnPlateau(t *testing.T) {
+	s := solver.NewReduceOnPlateau(0.5, 2, 0.01, 0.2)
+	for _, loss := range []float64{1, 0.9, 0.895, 0.89} {
+		s.Observe(loss)
+	}
+	assert.Equal(t, 0.5, s.Rate(1, 5))
+	s.Observe(0.5)
+	s.Observe(0.5)
+	s.Observe(0.5)
+	assert.Equal(t, 0.25, s.Rate(1, 8))
+	s.Observe(0.5)
+	s.Observe(0.5)
+	assert.Equal(t, 0.2, s.Rate(1, 10))
+}
+
+func Test_Scheduled(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(&entities.Config{
+		Inputs:     2,
+		Layout:     []int{8, 1},
+		Activation: entities.ActivationTanh,
+		Mode:       entities.ModeBinary,
+		Weight:     synapse.NewUniform(1, 0),
+		Bias:       true,
+	})
+
+	s := solver.NewScheduled(solver.NewAdam(0.05, 0, 0, 0), solver.NewCosineAnnealing(500, 0, 0.001))
+	services.NewBatchTrainer(s, 0, 4, 1).Train(context.Background(), n, xor, xor, 499)
+	assert.InDelta(t, solver.NewCosineAnnealing(500, 0, 0.001).Rate(0.05, 499), s.LearningRate(), 1e-12)
+
+	for _, e := range xor {
+		assert.InDelta(t, e.Response[0], n.Predict(e.Input)[0], 0.2)
+	}
+}
+
+func Test_ScheduledOnPlateau(t *testing.T) {
+	rand.Seed(0)
+	n := services.NewNeural(&entities.Config{
+		Inputs:     2,
+		Layout:     []int{1},
+		Activation: entities.ActivationTanh,
+		Mode:       entities.ModeBinary,
+		Bias:       true,
+	})
+
+	// A linear model cannot fit xor, so the validation loss stalls
+	s := solver.NewScheduled(solver.NewSGD(0.1, 0, 0, false), solver.NewReduceOnPlateau(0.5, 5, 1e-3, 0))
+	services.NewTrainer(s, 0).Train(context.Background(), n, xor, xor, 200)
+	assert.Less(t, s.LearningRate(), 0.1)
+}
@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/layer/neuron/synapse"
+	"main/internal/neural_net/application/services/solver"
+	"main/internal/neural_net/domain/entities"
+	nnErrors "main/internal/neural_net/domain/errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func xorSearch() *services.Search {
+	return &services.Search{
+		Config: &entities.Config{
+			Inputs:     2,
+			Activation: entities.ActivationTanh,
+			Mode:       entities.ModeBinary,
+			Weight:     synapse.NewUniform(1, 0),
+			Bias:       true,
+			Seed:       1,
+		},
+		Trainer: entities.TrainerConfig{Shuffle: entities.ShuffleNone},
+		Solver: func(lr float64) solver.Solver {
+			return solver.NewAdam(lr, 0, 0, 0)
+		},
+		Space: services.SearchSpace{
+			Layouts:       [][]int{{1}, {8, 1}},
+			LearningRates: []float64{0.0001, 0.05},
+		},
+		Epochs:  200,
+		Workers: 2,
+	}
+}
+
+func Test_GridSearch(t *testing.T) {
+	res, err := xorSearch().Grid(context.Background(), xor, xor)
+	assert.Nil(t, err)
+
+	assert.Len(t, res.Leaderboard, 4)
+	best := res.Leaderboard[0]
+	assert.Equal(t, []int{8, 1}, best.Layout)
+	assert.Equal(t, 0.05, best.LearningRate)
+	assert.Equal(t, 200, best.Epochs)
+	for i := 1; i < len(res.Leaderboard); i++ {
+		assert.LessOrEqual(t, res.Leaderboard[i-1].Score, res.Leaderboard[i].Score)
+	}
+	assert.Equal(t, best.Score, services.CrossValidate(services.FromDump(res.Best), xor))
+}
+
+func Test_RandomSearch(t *testing.T) {
+	s := xorSearch()
+	s.Space.BatchSizes = []int{1, 2, 4}
+	res, err := s.Random(context.Background(), xor, xor, 3, rand.New(rand.NewSource(1)))
+	assert.Nil(t, err)
+
+	assert.Len(t, res.Leaderboard, 3)
+	for _, r := range res.Leaderboard {
+		assert.Contains(t, s.Space.Layouts, r.Layout)
+		assert.Contains(t, s.Space.LearningRates, r.LearningRate)
+		assert.Contains(t, s.Space.BatchSizes, r.BatchSize)
+	}
+	assert.NotNil(t, res.Best)
+}
+
+func Test_SuccessiveHalving(t *testing.T) {
+	s := xorSearch()
+	s.Space.Layouts = [][]int{{1}, {4, 1}, {8, 1}}
+	s.Space.LearningRates = []float64{0.0001, 0.01, 0.05}
+	res, err := s.SuccessiveHalving(context.Background(), xor, xor, 0, 3, nil)
+	assert.Nil(t, err)
+
+	// 9 candidates for 200/3 epochs, then the best 3 for 200
+	assert.Len(t, res.Leaderboard, 9)
+	for i, r := range res.Leaderboard {
+		if i < 3 {
+			assert.Equal(t, 200, r.Epochs)
+		} else {
+			assert.Equal(t, 66, r.Epochs)
+		}
+	}
+	assert.NotEqual(t, []int{1}, res.Leaderboard[0].Layout)
+	assert.Equal(t, res.Leaderboard[0].Score, services.CrossValidate(services.FromDump(res.Best), xor))
+}
+
+func Test_SearchFailedTrial(t *testing.T) {
+	s := xorSearch()
+	s.Solver = func(lr float64) solver.Solver { return solver.NewSGD(lr, 0, 0, false) }
+	s.Space.LearningRates = []float64{0.1, 1e300}
+	res, err := s.Grid(context.Background(), xor, xor)
+	assert.Nil(t, err)
+
+	last := res.Leaderboard[len(res.Leaderboard)-1]
+	assert.ErrorIs(t, last.Err, nnErrors.ErrDiverged)
+	assert.True(t, math.IsNaN(last.Score))
+	assert.Nil(t, res.Leaderboard[0].Err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.Grid(ctx, xor, xor)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func Test_SearchResolvedConfig(t *testing.T) {
+	s := xorSearch()
+	s.Config.Layout = []int{8, 1}
+	services.NewNeural(s.Config)
+	s.Solver = nil
+	s.Space = services.SearchSpace{Layouts: [][]int{{4, 1}}, Activations: []entities.ActivationType{entities.ActivationReLU}}
+	s.Epochs = 10
+
+	res, err := s.Grid(context.Background(), xor, xor)
+	assert.Nil(t, err)
+	assert.Equal(t, entities.ActivationReLU, res.Best.Config.Layers[0].Activation)
+	assert.Equal(t, entities.ActivationSigmoid, res.Best.Config.Layers[1].Activation)
+	assert.Equal(t, entities.ActivationTanh, s.Config.Layers[0].Activation)
+
+	_, err = s.Random(context.Background(), xor, xor, 1, nil)
+	assert.NotNil(t, err)
+	_, err = s.SuccessiveHalving(context.Background(), xor, xor, 2, 0, nil)
+	assert.NotNil(t, err)
+}
+
+func Test_SearchInvalidTrial(t *testing.T) {
+	s := xorSearch()
+	s.Config.Mode, s.Config.ClassWeights = entities.ModeMultiClass, []float64{1, 2}
+	s.Space = services.SearchSpace{Layouts: [][]int{{4, 2}, {4, 3}}}
+	s.Epochs = 1
+	data := services.Examples{
+		{Input: []float64{0, 0}, Response: []float64{1, 0}},
+		{Input: []float64{1, 1}, Response: []float64{0, 1}},
+	}
+
+	res, err := s.Grid(context.Background(), data, data)
+	assert.Nil(t, err)
+	assert.Nil(t, res.Leaderboard[0].Err)
+	last := res.Leaderboard[1]
+	assert.ErrorIs(t, last.Err, nnErrors.ErrInvalidConfig)
+	assert.True(t, math.IsNaN(last.Score))
+}
+
+func Test_SearchDeeperTrial(t *testing.T) {
+	s := xorSearch()
+	s.Config.Layout, s.Config.Mode = []int{4, 1}, entities.ModeRegression
+	services.NewNeural(s.Config)
+	s.Space = services.SearchSpace{Layouts: [][]int{{4, 4, 1}}}
+	s.Epochs = 1
+
+	res, err := s.Grid(context.Background(), xor, xor)
+	assert.Nil(t, err)
+	layers := res.Best.Config.Layers
+	assert.True(t, *layers[0].Bias)
+	assert.True(t, *layers[1].Bias)
+	assert.False(t, *layers[2].Bias)
+	assert.Equal(t, entities.ActivationTanh, layers[1].Activation)
+}
+
+func Test_SearchNoValidation(t *testing.T) {
+	_, err := xorSearch().Grid(context.Background(), xor, nil)
+	assert.NotNil(t, err)
+}
+
+func Test_TrialString(t *testing.T) {
+	trial := services.Trial{Layout: []int{4, 1}, Activation: entities.ActivationReLU, LearningRate: 0.01, BatchSize: 8}
+	assert.Equal(t, "layout [4 1], activation ReLU, lr 0.01, batch 8", trial.String())
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/solver"
+	"main/internal/neural_net/domain/entities"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func seededConfig(seed int64) *entities.Config {
+	c := checkpointConfig()
+	c.Seed = seed
+	return c
+}
+
+func Test_ConfigSeed(t *testing.T) {
+	rand.Seed(1)
+	a := services.NewNeural(seededConfig(7))
+	rand.Seed(2)
+	b := services.NewNeural(seededConfig(7))
+	assert.Equal(t, a.Weights(), b.Weights())
+	assert.NotEqual(t, a.Weights(), services.NewNeural(seededConfig(8)).Weights())
+
+	// An unset seed is drawn, and recorded in the dump
+	n := services.NewNeural(seededConfig(0))
+	assert.NotZero(t, n.Config.Seed)
+	bytes, err := n.Marshal()
+	assert.Nil(t, err)
+	var dump services.Dump
+	assert.Nil(t, json.Unmarshal(bytes, &dump))
+	dump.Config.Weight = checkpointConfig().Weight
+	assert.Equal(t, n.Weights(), services.NewNeural(dump.Config).Weights())
+}
+
+type seeded interface {
+	services.Trainer
+	Seed() int64
+}
+
+func Test_TrainerSeed(t *testing.T) {
+	trainers := map[string]func() seeded{
+		"online": func() seeded { return services.NewTrainer(solver.NewAdam(0.01, 0, 0, 0), 0).WithSeed(3) },
+		"batch":  func() seeded { return services.NewBatchTrainer(solver.NewAdam(0.01, 0, 0, 0), 0, 2, 2).WithSeed(3) },
+	}
+
+	for name, newTrainer := range trainers {
+		// Concurrent jobs do not disturb each other
+		nets := make([]*services.Neural, 4)
+		wg := sync.WaitGroup{}
+		for i := range nets {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				nets[i] = services.NewNeural(seededConfig(5))
+				trainer := newTrainer()
+				_, err := trainer.Train(context.Background(), nets[i], xor, nil, 20)
+				assert.Nil(t, err)
+				assert.Equal(t, int64(3), trainer.Seed())
+			}(i)
+		}
+		wg.Wait()
+		for _, n := range nets[1:] {
+			assert.Equal(t, nets[0].Weights(), n.Weights(), name)
+		}
+	}
+}
+
+func Test_ExamplesWithRand(t *testing.T) {
+	exs := make(services.Examples, 20)
+	for i := range exs {
+		exs[i] = services.Example{Input: []float64{float64(i)}}
+	}
+
+	a := append(services.Examples{}, exs...)
+	a.ShuffleWith(rand.New(rand.NewSource(1)))
+	b := append(services.Examples{}, exs...)
+	b.ShuffleWith(rand.New(rand.NewSource(1)))
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, exs, a)
+	assert.ElementsMatch(t, exs, a)
+
+	first, second := exs.SplitWith(0.5, rand.New(rand.NewSource(1)))
+	again, _ := exs.SplitWith(0.5, rand.New(rand.NewSource(1)))
+	assert.Equal(t, first, again)
+	assert.Len(t, second, len(exs)-len(first))
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"main/internal/neural_net/application/services"
+	"main/internal/neural_net/application/services/solver"
+	"main/internal/neural_net/domain/entities"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+// trainWith trains a network seeded with 1 for the given epochs, returning
+// its weights
+func trainWith(t *testing.T, c entities.TrainerConfig, seed int64, epochs int) [][][]float64 {
+	config := normConfig(entities.NormNone)
+	config.Seed = 1
+	n := services.NewNeural(config)
+	_, err := services.NewTrainerWithConfig(solver.NewSGD(0.1, 0.9, 0, false), c).
+		WithSeed(seed).
+		Train(context.Background(), n, xor, nil, epochs)
+	assert.Nil(t, err)
+	return n.Weights()
+}
+
+func Test_TrainerConfigWrappers(t *testing.T) {
+	online := trainWith(t, entities.TrainerConfig{}, 2, 5)
+	config := normConfig(entities.NormNone)
+	config.Seed = 1
+	n := services.NewNeural(config)
+	_, err := services.NewTrainer(solver.NewSGD(0.1, 0.9, 0, false), 0).WithSeed(2).Train(context.Background(), n, xor, nil, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, online, n.Weights())
+
+	batch := trainWith(t, entities.TrainerConfig{BatchSize: 2, Parallelism: 2}, 2, 5)
+	n = services.NewNeural(config)
+	_, err = services.NewBatchTrainer(solver.NewSGD(0.1, 0.9, 0, false), 0, 2, 2).WithSeed(2).Train(context.Background(), n, xor, nil, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, batch, n.Weights())
+}
+
+func Test_OnlineMiniBatch(t *testing.T) {
+	sequential := trainWith(t, entities.TrainerConfig{BatchSize: 2}, 2, 5)
+	parallel := trainWith(t, entities.TrainerConfig{BatchSize: 2, Parallelism: 2}, 2, 5)
+	for i := range sequential {
+		for j := range sequential[i] {
+			assert.InDeltaSlice(t, sequential[i][j], parallel[i][j], 1e-12)
+		}
+	}
+	assert.NotEqual(t, trainWith(t, entities.TrainerConfig{}, 2, 5), sequential)
+}
+
+func Test_ShufflePolicy(t *testing.T) {
+	// Unshuffled, the order of the examples does not depend on the seed
+	none := entities.TrainerConfig{Shuffle: entities.ShuffleNone}
+	assert.Equal(t, trainWith(t, none, 2, 3), trainWith(t, none, 3, 3))
+	assert.NotEqual(t, trainWith(t, entities.TrainerConfig{}, 2, 3), trainWith(t, entities.TrainerConfig{}, 3, 3))
+
+	// Shuffled once, every epoch sees the order of the first one
+	once := entities.TrainerConfig{Shuffle: entities.ShuffleOnce}
+	assert.Equal(t, trainWith(t, entities.TrainerConfig{}, 2, 1), trainWith(t, once, 2, 1))
+	assert.NotEqual(t, trainWith(t, entities.TrainerConfig{}, 2, 3), trainWith(t, once, 2, 3))
+}
+
+func Test_ValidationFrequency(t *testing.T) {
+	n := services.NewNeural(normConfig(entities.NormNone))
+	var validated []int
+	cb := services.CallbackFuncs{
+		EpochEnd: func(p *services.Progress) error {
+			if !math.IsNaN(p.Metric(entities.MetricLoss)) {
+				validated = append(validated, p.Epoch)
+			}
+			return nil
+		},
+	}
+	trainer := services.NewTrainerWithConfig(solver.NewSGD(0.1, 0, 0, false), entities.TrainerConfig{ValidationFrequency: 3})
+	_, err := trainer.WithCallbacks(cb).Train(context.Background(), n, xor, xor, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{3, 6, 9}, validated)
+
+	// Patience counts evaluations
+	linear := services.NewNeural(&entities.Config{
+		Inputs:     2,
+		Layout:     []int{1},
+		Activation: entities.ActivationTanh,
+		Mode:       entities.ModeBinary,
+		Bias:       true,
+		Seed:       1,
+	})
+	trainer = services.NewTrainerWithConfig(solver.NewSGD(0.5, 0, 0, false), entities.TrainerConfig{ValidationFrequency: 5}).
+		WithEarlyStopping(entities.EarlyStopping{Patience: 2, MinDelta: 1e-4})
+	result, err := trainer.Train(context.Background(), linear, xor, xor, 1000)
+	assert.Nil(t, err)
+	assert.Equal(t, entities.StopEarly, result.Stop)
+	assert.Equal(t, 0, result.Epochs%5)
+	assert.Equal(t, result.BestEpoch+2*5, result.Epochs)
+}
+
+func Test_TrainerMetrics(t *testing.T) {
+	r, w, err := os.Pipe()
+	assert.Nil(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	trainer := services.NewTrainerWithConfig(solver.NewSGD(0.1, 0, 0, false), entities.TrainerConfig{
+		Metrics:   []entities.MetricType{entities.MetricAccuracy, entities.MetricLoss},
+		Verbosity: 2,
+	})
+	os.Stdout = stdout
+
+	n := services.NewNeural(normConfig(entities.NormNone))
+	_, err = trainer.Train(context.Background(), n, xor, xor, 4)
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+	out, err := io.ReadAll(r)
+	assert.Nil(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.Len(t, lines, 4)
+	assert.Equal(t, []string{"Epochs", "Elapsed", "Accuracy", "Loss", "(BinCE)", "LR"}, strings.Fields(lines[0]))
+	assert.Regexp(t, `^2 +\S+ +\d\.\d{2} +\d\.\d{4} +0\.1 *$`, lines[2])
+	assert.Regexp(t, `^4 `, lines[3])
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"main/internal/neural_net/application/services"
@@ -38,7 +39,7 @@ func Test_BoundedRegression(t *testing.T) {
 		})
 
 		trainer := services.NewTrainer(solver.NewSGD(0.25, 0.5, 0, false), 0)
-		trainer.Train(n, data, nil, 5000)
+		trainer.Train(context.Background(), n, data, nil, 5000)
 
 		tests := []float64{0.0, 0.1, 0.25, 0.5, 0.75, 0.9}
 		for _, x := range tests {
@@ -63,7 +64,7 @@ func Test_RegressionLinearOuts(t *testing.T) {
 		Bias:       true,
 	})
 	trainer := services.NewBatchTrainer(solver.NewAdam(0.01, 0, 0, 0), 0, 25, 2)
-	trainer.Train(n, squares, nil, 25000)
+	trainer.Train(context.Background(), n, squares, nil, 25000)
 	fmt.Println(fmt.Sprintf("%v", n.Predict([]float64{0.2})))
 
 	for i := 0; i < 100; i++ {
@@ -92,7 +93,7 @@ func Test_Training(t *testing.T) {
 	})
 
 	trainer := services.NewTrainer(solver.NewSGD(0.5, 0.1, 0, false), 0)
-	trainer.Train(n, data, nil, 1000)
+	trainer.Train(context.Background(), n, data, nil, 1000)
 
 	v := n.Predict([]float64{0})
 	assert.InEpsilon(t, 1, 1+v[0], 0.1)
@@ -125,7 +126,7 @@ func Test_Prediction(t *testing.T) {
 	})
 	trainer := services.NewTrainer(solver.NewSGD(0.5, 0.1, 0, false), 0)
 
-	trainer.Train(n, data, nil, 5000)
+	trainer.Train(context.Background(), n, data, nil, 5000)
 
 	for _, d := range data {
 		assert.InEpsilon(t, n.Predict(d.Input)[0]+1, d.Response[0]+1, 0.1)
@@ -143,7 +144,7 @@ func Test_CrossVal(t *testing.T) {
 	})
 
 	trainer := services.NewTrainer(solver.NewSGD(0.5, 0.1, 0, false), 0)
-	trainer.Train(n, data, data, 1000)
+	trainer.Train(context.Background(), n, data, data, 1000)
 
 	for _, d := range data {
 		assert.InEpsilon(t, n.Predict(d.Input)[0]+1, d.Response[0]+1, 0.1)
@@ -176,7 +177,7 @@ func Test_MultiClass(t *testing.T) {
 	})
 
 	trainer := services.NewTrainer(solver.NewSGD(0.01, 0.1, 0, false), 0)
-	trainer.Train(n, data, data, 1000)
+	trainer.Train(context.Background(), n, data, data, 1000)
 
 	for _, d := range data {
 		est := n.Predict(d.Input)
@@ -210,7 +211,7 @@ func Test_or(t *testing.T) {
 
 	trainer := services.NewTrainer(solver.NewSGD(0.5, 0, 0, false), 10)
 
-	trainer.Train(n, permutations, permutations, 25)
+	trainer.Train(context.Background(), n, permutations, permutations, 25)
 
 	for _, perm := range permutations {
 		assert.Equal(t, utils.Round(n.Predict(perm.Input)[0]), perm.Response[0])
@@ -227,6 +228,7 @@ func Test_xor(t *testing.T) {
 		Activation: entities.ActivationSigmoid,
 		Mode:       entities.ModeBinary,
 		Weight:     synapse.NewUniform(.25, 0),
+		Seed:       2,
 		Bias:       true,
 	})
 	permutations := services.Examples{
@@ -237,7 +239,7 @@ func Test_xor(t *testing.T) {
 	}
 
 	trainer := services.NewTrainer(solver.NewSGD(1, 0.1, 1e-6, false), 50)
-	trainer.Train(n, permutations, permutations, 1000)
+	trainer.Train(context.Background(), n, permutations, permutations, 1000)
 
 	for _, perm := range permutations {
 		assert.InEpsilon(t, n.Predict(perm.Input)[0]+1, perm.Response[0]+1, 0.2)
@@ -246,6 +248,42 @@ func Test_xor(t *testing.T) {
 
 }
 
+func Test_xor_solvers(t *testing.T) {
+	solvers := map[string]solver.Solver{
+		"RMSProp":  solver.NewRMSProp(0.01, 0, 0),
+		"Adagrad":  solver.NewAdagrad(0.5, 0),
+		"Adadelta": solver.NewAdadelta(0, 0, 0),
+		"AdamW":    solver.NewAdamW(0.05, 0, 0, 0, 0.001),
+		"Nadam":    solver.NewNadam(0.05, 0, 0, 0),
+		"AMSGrad":  solver.NewAMSGrad(0.05, 0, 0, 0),
+	}
+	permutations := services.Examples{
+		{[]float64{0, 0}, []float64{0}},
+		{[]float64{1, 0}, []float64{1}},
+		{[]float64{0, 1}, []float64{1}},
+		{[]float64{1, 1}, []float64{0}},
+	}
+
+	for name, s := range solvers {
+		rand.Seed(0)
+		n := services.NewNeural(&entities.Config{
+			Inputs:     2,
+			Layout:     []int{8, 1},
+			Activation: entities.ActivationTanh,
+			Mode:       entities.ModeBinary,
+			Weight:     synapse.NewUniform(1, 0),
+			Bias:       true,
+		})
+
+		trainer := services.NewTrainer(s, 0)
+		trainer.Train(context.Background(), n, permutations, permutations, 1000)
+
+		for _, perm := range permutations {
+			assert.InDelta(t, perm.Response[0], n.Predict(perm.Input)[0], 0.2, name)
+		}
+	}
+}
+
 func printResult(ideal, actual []float64) {
 	fmt.Printf("want: %+v have: %+v\n", ideal, actual)
 }
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"fmt"
+	nnErrors "main/internal/neural_net/domain/errors"
+	"main/internal/neural_net/domain/ports"
+	"sync"
+)
+
+// ActivationFactory builds a custom activation given the activation
+// parameter of the layer (zero when unset)
+type ActivationFactory func(alpha float64) ports.Differentiable
+
+var activations = struct {
+	sync.RWMutex
+	factories map[string]ActivationFactory
+}{factories: make(map[string]ActivationFactory)}
+
+// RegisterActivation makes a custom activation available under name, for
+// use in Config.ActivationName and LayerConfig.ActivationName. Activations
+// may be shared by concurrent predictions and must be safe for concurrent
+// use. It panics if name is empty, already registered or factory is nil
+func RegisterActivation(name string, factory ActivationFactory) {
+	activations.Lock()
+	defer activations.Unlock()
+	if name == "" || factory == nil {
+		panic("neural_net: RegisterActivation needs a name and a factory")
+	}
+	if _, dup := activations.factories[name]; dup {
+		panic("neural_net: RegisterActivation called twice for activation " + name)
+	}
+	activations.factories[name] = factory
+}
+
+// RegisteredActivation returns the custom activation registered under name
+func RegisteredActivation(name string, alpha float64) (ports.Differentiable, error) {
+	activations.RLock()
+	factory, ok := activations.factories[name]
+	activations.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", nnErrors.ErrUnknownActivation, name)
+	}
+	return factory(alpha), nil
+}
@@ -20,8 +20,9 @@ func OutputActivation(c entities.Mode) entities.ActivationType {
 	return entities.ActivationNone
 }
 
-// GetActivation returns the concrete activation given an ActivationType
-func GetActivation(act entities.ActivationType) ports.Differentiable {
+// GetActivation returns the concrete activation given an ActivationType and
+// its parameter, where zero selects the default
+func GetActivation(act entities.ActivationType, alpha float64) ports.Differentiable {
 	switch act {
 	case entities.ActivationSigmoid:
 		return &activation.Sigmoid{}
@@ -33,6 +34,20 @@ func GetActivation(act entities.ActivationType) ports.Differentiable {
 		return &activation.Linear{}
 	case entities.ActivationSoftmax:
 		return &activation.Linear{}
+	case entities.ActivationLeakyReLU:
+		return &activation.LeakyReLU{Slope: Fparam(alpha, 0.01)}
+	case entities.ActivationELU:
+		return &activation.ELU{Alpha: Fparam(alpha, 1)}
+	case entities.ActivationSELU:
+		return &activation.SELU{}
+	case entities.ActivationGELU:
+		return &activation.GELU{}
+	case entities.ActivationSwish:
+		return &activation.Swish{}
+	case entities.ActivationSoftplus:
+		return &activation.Softplus{}
+	case entities.ActivationHardSigmoid:
+		return &activation.HardSigmoid{}
 	}
 	return &activation.Linear{}
 }
@@ -143,6 +158,12 @@ func Sum(xx []float64) (sum float64) {
 // Softmax is the softmax function
 func Softmax(xx []float64) []float64 {
 	out := make([]float64, len(xx))
+	SoftmaxTo(out, xx)
+	return out
+}
+
+// SoftmaxTo writes the softmax of xx into out, which may alias xx
+func SoftmaxTo(out, xx []float64) {
 	var sum float64
 	max := Max(xx)
 	for i, x := range xx {
@@ -152,7 +173,6 @@ func Softmax(xx []float64) []float64 {
 	for i := range out {
 		out[i] /= sum
 	}
-	return out
 }
 
 // Round to nearest integer
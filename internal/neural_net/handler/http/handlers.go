@@ -2,10 +2,15 @@ package http
 
 import (
 	"context"
+	"errors"
 	"github.com/gofiber/fiber/v2"
 	"main/config"
+	ent "main/internal/neural_net/domain/entities"
+	nnErrors "main/internal/neural_net/domain/errors"
 	"main/internal/neural_net/domain/ports"
 	"main/pkg/logger"
+	cm "main/pkg/utils/common"
+	"main/pkg/utils/validator"
 )
 
 // handlerHttp Auth handlers
@@ -25,3 +30,50 @@ var (
 func NewHttpHandler(ctx context.Context, cfg *config.Config, service ports.IService, logger logger.Logger) ports.IHandlers {
 	return &handlerHttp{ctx: ctx, cfg: cfg, service: service, logger: logger}
 }
+
+// Predict godoc
+// @Summary Predict
+// @Description Prediction of the trained neural net
+// @Tags NeuralNet
+// @Param Body body entities.PredictReq true "`Body for prediction`"
+// @Accept json
+// @Produce json
+// @Success 200 {object} entities.HandlerResponse{}
+// @Router /neural_net/predict [post]
+func (h handlerHttp) Predict(c *fiber.Ctx) error {
+	// Predictions are served concurrently, so the response is kept local
+	// instead of using the shared Responser and StatusCode
+	var (
+		responser  fiber.Map
+		statusCode int
+	)
+	dat := ent.PredictReq{}
+
+	errParser := c.BodyParser(&dat)
+	if errParser != nil {
+		statusCode = fiber.StatusBadGateway
+		responser = cm.HTTPResponser(nil, statusCode, true, errParser.Error())
+	}
+
+	errValidate := validator.ValidateStruct(c.Context(), &dat)
+	if errValidate != nil {
+		statusCode = fiber.StatusBadRequest
+		responser = cm.HTTPResponser(nil, statusCode, true, errValidate.Error())
+	}
+
+	if errParser == nil && errValidate == nil {
+		prediction, err := h.service.Predict(dat.Input)
+		if errors.Is(err, nnErrors.ErrNotTrained) {
+			statusCode = fiber.StatusServiceUnavailable
+			responser = cm.HTTPResponser(nil, statusCode, true, err.Error())
+		} else if err != nil {
+			statusCode = fiber.StatusBadRequest
+			responser = cm.HTTPResponser(nil, statusCode, true, err.Error())
+		} else {
+			statusCode = fiber.StatusOK
+			responser = cm.HTTPResponser(prediction, statusCode, false, "İşlem Başarılı")
+		}
+	}
+
+	return c.Status(statusCode).JSON(responser)
+}
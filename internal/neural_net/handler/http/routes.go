@@ -7,7 +7,8 @@ import (
 
 // MapRoutes Auth Domain routes
 func MapRoutes(h ports.IHandlers, router fiber.Router) {
-	//neuralNet := router.Group("/auth")
+	neuralNet := router.Group("/neural_net")
+	neuralNet.Post("/predict", h.Predict)
 
 	/* Example HTTP handler Methods */
 	//auth.Get("/:id", h.Login)
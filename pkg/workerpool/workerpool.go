@@ -10,6 +10,7 @@ type T = interface{}
 type WorkerPool interface {
 	Run()
 	AddTask(task func())
+	Stop()
 }
 
 type workerPool struct {
@@ -35,6 +36,12 @@ func (wp *workerPool) AddTask(task func()) {
 	wp.queuedTaskC <- task
 }
 
+// Stop makes the workers exit once they are done with their current task.
+// No task may be added afterwards
+func (wp *workerPool) Stop() {
+	close(wp.queuedTaskC)
+}
+
 func (wp *workerPool) GetTotalQueuedTask() int {
 	return len(wp.queuedTaskC)
 }